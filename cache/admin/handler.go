@@ -0,0 +1,235 @@
+// Package admin provides an HTTP endpoint for operating the caches of a running process.
+//
+// The handler is meant to be mounted on an internal (debug/admin) port:
+//
+//	mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", admin.NewHandler(registry)))
+//
+// Routes:
+//
+//	GET  /caches                       lists all registered caches
+//	GET  /caches/stats?name=N          prints stats of the cache N
+//	GET  /caches/keys?name=N[&limit=L] dumps the keys of the cache N
+//	POST /caches/purge?name=N[&prefix=P] clears the cache N or removes the keys with prefix P
+//	POST /caches/resize?name=N&cap=C   sets the capacity of the cache N to C
+//
+// Listing needs a registry implementing cache.RegistryLister (like the one of cache.NewRegistry).
+// All responses are JSON encoded. cmd/cachectl is the command-line client for this API.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/catalystgo/cache-go/cache"
+)
+
+var (
+	// ErrNotFound is returned when there is no cache with the requested name.
+	ErrNotFound = errors.New("cache not found")
+	// ErrNotSupported is returned when the cache does not support the requested operation.
+	ErrNotSupported = errors.New("operation is not supported by the cache")
+)
+
+// Stats describes a single cache.
+type Stats struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Len  int    `json:"len"`
+	Cap  int    `json:"cap"`
+}
+
+// Keys is the response of the keys route.
+type Keys struct {
+	Name  string   `json:"name"`
+	Total int      `json:"total"`
+	Keys  []string `json:"keys"`
+}
+
+// Purge is the response of the purge route.
+type Purge struct {
+	Name    string `json:"name"`
+	Prefix  string `json:"prefix,omitempty"`
+	Removed int    `json:"removed"`
+}
+
+// Resize is the response of the resize route.
+type Resize struct {
+	Name   string `json:"name"`
+	OldCap int    `json:"old_cap"`
+	NewCap int    `json:"new_cap"`
+}
+
+// Error is the response body in case of an error.
+type Error struct {
+	Error string `json:"error"`
+}
+
+type handler struct {
+	registry cache.Registry
+}
+
+// NewHandler creates an http.Handler serving the admin API over the caches of the registry.
+func NewHandler(registry cache.Registry) http.Handler {
+	h := handler{registry: registry}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /caches", h.list)
+	mux.HandleFunc("GET /caches/stats", h.stats)
+	mux.HandleFunc("GET /caches/keys", h.keys)
+	mux.HandleFunc("POST /caches/purge", h.purge)
+	mux.HandleFunc("POST /caches/resize", h.resize)
+
+	return mux
+}
+
+func (h handler) list(w http.ResponseWriter, _ *http.Request) {
+	lister, ok := h.registry.(cache.RegistryLister)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("can't list the caches: %w", ErrNotSupported))
+		return
+	}
+
+	caches := lister.List()
+	stats := make([]Stats, 0, len(caches))
+	for _, c := range caches {
+		stats = append(stats, statsOf(c))
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (h handler) stats(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, statsOf(c))
+}
+
+func (h handler) keys(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("wrong limit %q", v))
+			return
+		}
+		limit = l
+	}
+
+	kg, ok := c.(cache.KeysGetter)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrNotSupported)
+		return
+	}
+
+	keys := kg.Keys()
+	resp := Keys{Name: c.Name(), Total: len(keys)}
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	resp.Keys = make([]string, 0, len(keys))
+	for _, k := range keys {
+		resp.Keys = append(resp.Keys, fmt.Sprint(k))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h handler) purge(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		removed := c.Len()
+		c.Clear()
+		writeJSON(w, http.StatusOK, Purge{Name: c.Name(), Removed: removed})
+		return
+	}
+
+	var removed int
+	if remover, ok := c.(cache.PrefixRemover); ok {
+		// the caches with a key index find the keys without a scan
+		removed = remover.RemoveByPrefix(prefix)
+	} else {
+		kg, ok := c.(cache.KeysGetter)
+		if !ok {
+			writeError(w, http.StatusNotImplemented, ErrNotSupported)
+			return
+		}
+		for _, k := range cache.FilterKeys(kg.Keys(), cache.HasPrefix(prefix)) {
+			c.Remove(k)
+			removed++
+		}
+	}
+	writeJSON(w, http.StatusOK, Purge{Name: c.Name(), Prefix: prefix, Removed: removed})
+}
+
+func (h handler) resize(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	v := r.URL.Query().Get("cap")
+	capacity, err := strconv.Atoi(v)
+	if err != nil || capacity <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("wrong cap %q: %w", v, cache.ErrWrongCapacity))
+		return
+	}
+
+	cs, ok := c.(cache.CapSetter)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrNotSupported)
+		return
+	}
+
+	oldCap := c.Cap()
+	if err = cs.SetCap(capacity); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Resize{Name: c.Name(), OldCap: oldCap, NewCap: c.Cap()})
+}
+
+func (h handler) lookup(w http.ResponseWriter, r *http.Request) (cache.NamedCache, bool) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("name is required"))
+		return nil, false
+	}
+	c, ok := h.registry.GetByName(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %#q", ErrNotFound, name))
+		return nil, false
+	}
+	return c, true
+}
+
+func statsOf(c cache.NamedCache) Stats {
+	return Stats{
+		Name: c.Name(),
+		Type: fmt.Sprintf("%T", c),
+		Len:  c.Len(),
+		Cap:  c.Cap(),
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,209 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/admin"
+	"github.com/catalystgo/cache-go/cache/arc"
+	"github.com/catalystgo/cache-go/cache/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *lru.Cache) {
+	lruCache, err := lru.NewCache("/Service/LRU", 10, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lruCache.Close() })
+
+	arcCache, err := arc.NewCache("/Service/ARC", 5, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = arcCache.Close() })
+
+	r := cache.NewRegistry()
+	require.NoError(t, r.Register(lruCache, arcCache))
+
+	srv := httptest.NewServer(admin.NewHandler(r))
+	t.Cleanup(srv.Close)
+
+	return srv, lruCache
+}
+
+func do(t *testing.T, method, url string, status int, resp interface{}) {
+	req, err := http.NewRequest(method, url, http.NoBody)
+	require.NoError(t, err)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, status, res.StatusCode)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(resp))
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		srv, c := newServer(t)
+		c.Put("a", 1)
+
+		// act
+		var got []admin.Stats
+		do(t, http.MethodGet, srv.URL+"/caches", http.StatusOK, &got)
+
+		// assert
+		require.Equal(t, []admin.Stats{
+			{Name: "/Service/ARC", Type: "*arc.Cache", Len: 0, Cap: 5},
+			{Name: "/Service/LRU", Type: "*lru.Cache", Len: 1, Cap: 10},
+		}, got)
+	})
+
+	t.Run("list not supported by registry", func(t *testing.T) {
+		t.Parallel()
+
+		// the embedded interface hides List of the registry
+		srv := httptest.NewServer(admin.NewHandler(struct{ cache.Registry }{cache.NewRegistry()}))
+		t.Cleanup(srv.Close)
+
+		// act
+		var got admin.Error
+		do(t, http.MethodGet, srv.URL+"/caches", http.StatusNotImplemented, &got)
+
+		// assert
+		require.Contains(t, got.Error, admin.ErrNotSupported.Error())
+	})
+
+	t.Run("stats of unknown cache", func(t *testing.T) {
+		t.Parallel()
+
+		srv, _ := newServer(t)
+
+		// act
+		var got admin.Error
+		do(t, http.MethodGet, srv.URL+"/caches/stats?name=unknown", http.StatusNotFound, &got)
+
+		// assert
+		require.Contains(t, got.Error, admin.ErrNotFound.Error())
+	})
+
+	t.Run("keys with limit", func(t *testing.T) {
+		t.Parallel()
+
+		srv, c := newServer(t)
+		c.Put("a", 1)
+		c.Put("b", 2)
+		c.Put("c", 3)
+
+		// act
+		var got admin.Keys
+		do(t, http.MethodGet, srv.URL+"/caches/keys?name=/Service/LRU&limit=2", http.StatusOK, &got)
+
+		// assert
+		require.Equal(t, admin.Keys{Name: "/Service/LRU", Total: 3, Keys: []string{"a", "b"}}, got)
+	})
+
+	t.Run("purge by prefix", func(t *testing.T) {
+		t.Parallel()
+
+		srv, c := newServer(t)
+		c.Put("user:42:profile", 1)
+		c.Put("user:42:orders", 2)
+		c.Put("user:43:profile", 3)
+
+		// act
+		var got admin.Purge
+		do(t, http.MethodPost, srv.URL+"/caches/purge?name=/Service/LRU&prefix=user:42:", http.StatusOK, &got)
+
+		// assert
+		require.Equal(t, 2, got.Removed)
+		require.Equal(t, []interface{}{"user:43:profile"}, c.Keys())
+	})
+
+	t.Run("purge by prefix with prefix remover", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("/Service/Indexed", 10, 0, lru.WithKeyIndex())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = base.Close() })
+		c := &prefixRemoverCache{NamedCache: base, remover: base}
+		r := cache.NewRegistry()
+		require.NoError(t, r.Register(c))
+		srv := httptest.NewServer(admin.NewHandler(r))
+		t.Cleanup(srv.Close)
+		c.Put("user:42:profile", 1)
+		c.Put("user:43:profile", 2)
+
+		// act
+		var got admin.Purge
+		do(t, http.MethodPost, srv.URL+"/caches/purge?name=/Service/Indexed&prefix=user:42:", http.StatusOK, &got)
+
+		// assert
+		require.Equal(t, 1, got.Removed)
+		require.Equal(t, []string{"user:42:"}, c.prefixes)
+		require.Equal(t, []interface{}{"user:43:profile"}, base.Keys())
+	})
+
+	t.Run("purge all", func(t *testing.T) {
+		t.Parallel()
+
+		srv, c := newServer(t)
+		c.Put("a", 1)
+		c.Put("b", 2)
+
+		// act
+		var got admin.Purge
+		do(t, http.MethodPost, srv.URL+"/caches/purge?name=/Service/LRU", http.StatusOK, &got)
+
+		// assert
+		require.Equal(t, 2, got.Removed)
+		require.Equal(t, 0, c.Len())
+	})
+
+	t.Run("resize", func(t *testing.T) {
+		t.Parallel()
+
+		srv, c := newServer(t)
+
+		// act
+		var got admin.Resize
+		do(t, http.MethodPost, srv.URL+"/caches/resize?name=/Service/LRU&cap=20", http.StatusOK, &got)
+
+		// assert
+		require.Equal(t, admin.Resize{Name: "/Service/LRU", OldCap: 10, NewCap: 20}, got)
+		require.Equal(t, 20, c.Cap())
+	})
+
+	t.Run("resize not supported", func(t *testing.T) {
+		t.Parallel()
+
+		srv, _ := newServer(t)
+
+		// act
+		var got admin.Error
+		do(t, http.MethodPost, srv.URL+"/caches/resize?name=/Service/ARC&cap=20", http.StatusNotImplemented, &got)
+
+		// assert
+		require.Equal(t, admin.ErrNotSupported.Error(), got.Error)
+	})
+}
+
+// prefixRemoverCache is a cache which removes by prefix but does not return its keys.
+type prefixRemoverCache struct {
+	cache.NamedCache
+	remover  cache.PrefixRemover
+	prefixes []string
+}
+
+func (c *prefixRemoverCache) RemoveByPrefix(prefix string) int {
+	c.prefixes = append(c.prefixes, prefix)
+	return c.remover.RemoveByPrefix(prefix)
+}
+
+func (c *prefixRemoverCache) RemoveMatching(match func(key interface{}) bool) int {
+	return c.remover.RemoveMatching(match)
+}
@@ -0,0 +1,219 @@
+package arc
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// policy is the ARC replacement policy (N. Megiddo, D. Modha, "ARC: A Self-Tuning, Low Overhead Replacement Cache")
+// over the simplelru lists, like lru.ARCCache: t1 keeps the keys used once and t2 the keys used again,
+// the ghost lists b1 and b2 keep the keys recently evicted from them, and the target size p of t1
+// adapts to the hits of the ghost lists.
+// Unlike lru.ARCCache it reports the evictions to onEvict and can evict on demand (see removeOldest),
+// so the cache keeps the total cost without scanning the keys.
+type policy struct {
+	mu   sync.Mutex
+	size int
+	// target is the target size of t1, p in the paper
+	target int
+	t1     *simplelru.LRU
+	b1     *simplelru.LRU
+	t2     *simplelru.LRU
+	b2     *simplelru.LRU
+	// onEvict is called under mu for every entry evicted by the policy, but not for the removed ones
+	onEvict simplelru.EvictCallback
+}
+
+func newPolicy(size int, onEvict simplelru.EvictCallback) (*policy, error) {
+	if size <= 0 {
+		return nil, errors.New("invalid size")
+	}
+
+	lists := make([]*simplelru.LRU, 4)
+	for i := range lists {
+		list, err := simplelru.NewLRU(size, nil)
+		if err != nil {
+			return nil, err
+		}
+		lists[i] = list
+	}
+
+	return &policy{
+		size:    size,
+		t1:      lists[0],
+		b1:      lists[1],
+		t2:      lists[2],
+		b2:      lists[3],
+		onEvict: onEvict,
+	}, nil
+}
+
+// Get looks up the value of the key, a key of t1 is promoted to t2.
+func (p *policy) Get(key interface{}) (value interface{}, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if value, ok = p.t1.Peek(key); ok {
+		p.t1.Remove(key)
+		p.t2.Add(key, value)
+		return value, true
+	}
+	return p.t2.Get(key)
+}
+
+// Add adds the value of the key, evicting an entry if the cache is full.
+func (p *policy) Add(key, value interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.t1.Contains(key) {
+		p.t1.Remove(key)
+		p.t2.Add(key, value)
+		return
+	}
+	if p.t2.Contains(key) {
+		p.t2.Add(key, value)
+		return
+	}
+
+	if p.b1.Contains(key) {
+		// t1 is too small, grow its target
+		delta := 1
+		if b1Len, b2Len := p.b1.Len(), p.b2.Len(); b2Len > b1Len {
+			delta = b2Len / b1Len
+		}
+		p.target = min(p.target+delta, p.size)
+
+		if p.t1.Len()+p.t2.Len() >= p.size {
+			p.replace(false, nil)
+		}
+		p.b1.Remove(key)
+		p.t2.Add(key, value)
+		return
+	}
+
+	if p.b2.Contains(key) {
+		// t2 is too small, shrink the target of t1
+		delta := 1
+		if b1Len, b2Len := p.b1.Len(), p.b2.Len(); b1Len > b2Len {
+			delta = b1Len / b2Len
+		}
+		p.target = max(p.target-delta, 0)
+
+		if p.t1.Len()+p.t2.Len() >= p.size {
+			p.replace(true, nil)
+		}
+		p.b2.Remove(key)
+		p.t2.Add(key, value)
+		return
+	}
+
+	if p.t1.Len()+p.t2.Len() >= p.size {
+		p.replace(false, nil)
+	}
+	// keep the ghost lists trimmed
+	if p.b1.Len() > p.size-p.target {
+		p.b1.RemoveOldest()
+	}
+	if p.b2.Len() > p.target {
+		p.b2.RemoveOldest()
+	}
+	p.t1.Add(key, value)
+}
+
+// replace evicts the oldest entry of t1 if it is over its target size, otherwise of t2, falling back to the other list
+// if the chosen one is empty. b2ContainsKey is set when the key being added is in b2. The key except is not evicted.
+// It reports whether an entry was evicted.
+func (p *policy) replace(b2ContainsKey bool, except interface{}) bool {
+	t1Len := p.t1.Len()
+	if t1Len > 0 && (t1Len > p.target || (t1Len == p.target && b2ContainsKey)) {
+		return p.evict(p.t1, p.b1, except) || p.evict(p.t2, p.b2, except)
+	}
+	return p.evict(p.t2, p.b2, except) || p.evict(p.t1, p.b1, except)
+}
+
+// removeOldest evicts an entry chosen by the policy, like on a put into the full cache. The key except is not evicted.
+// It reports whether an entry was evicted.
+func (p *policy) removeOldest(except interface{}) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.replace(false, except)
+}
+
+// evict evicts the oldest entry of the list into its ghost list, unless the list is empty or it is except.
+func (p *policy) evict(list, ghost *simplelru.LRU, except interface{}) bool {
+	key, value, ok := list.GetOldest()
+	if !ok || (except != nil && key == except) {
+		return false
+	}
+	list.RemoveOldest()
+	ghost.Add(key, nil)
+	p.onEvict(key, value)
+	return true
+}
+
+// Peek returns the value of the key without updating the recency or the frequency.
+func (p *policy) Peek(key interface{}) (value interface{}, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if value, ok = p.t1.Peek(key); ok {
+		return value, true
+	}
+	return p.t2.Peek(key)
+}
+
+// Contains checks for the presence of the key without updating the recency or the frequency.
+func (p *policy) Contains(key interface{}) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.t1.Contains(key) || p.t2.Contains(key)
+}
+
+// Remove removes the key, it is not reported as evicted.
+func (p *policy) Remove(key interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.t1.Remove(key) {
+		return
+	}
+	if p.t2.Remove(key) {
+		return
+	}
+	if p.b1.Remove(key) {
+		return
+	}
+	p.b2.Remove(key)
+}
+
+// Purge removes all the keys, they are not reported as evicted.
+func (p *policy) Purge() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.t1.Purge()
+	p.t2.Purge()
+	p.b1.Purge()
+	p.b2.Purge()
+}
+
+// Keys returns the keys, the ones used once first, from the oldest to the newest in each list.
+func (p *policy) Keys() []interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append(p.t1.Keys(), p.t2.Keys()...)
+}
+
+// Len returns the number of the entries.
+func (p *policy) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.t1.Len() + p.t2.Len()
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/catalystgo/cache-go/cache/arc"
 	"github.com/catalystgo/cache-go/cache/lru"
 	"github.com/catalystgo/cache-go/cache/ristretto"
+	"github.com/catalystgo/cache-go/cache/shardedlru"
+	"github.com/catalystgo/cache-go/cache/sieve"
 	"github.com/catalystgo/cache-go/cache/twoqueue"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -66,6 +68,18 @@ func createRistretto(t testing.TB) *ristretto.Cache {
 	return c
 }
 
+func createShardedLRU(t testing.TB) *shardedlru.Cache {
+	c, err := shardedlru.NewCache(name, capValue, ttlValue)
+	require.NoError(t, err)
+	return c
+}
+
+func createSIEVE(t testing.TB) *sieve.Cache {
+	c, err := sieve.NewCache(name, capValue, ttlValue)
+	require.NoError(t, err)
+	return c
+}
+
 // func createRealtime(t testing.TB, opts ...realtime.CacheOption) *realtime.Cache {
 // 	ctrl := gomock.NewController(t)
 // 	defer ctrl.Finish()
@@ -200,6 +214,24 @@ func TestBenchmarks(t *testing.T) {
 	t.Run("Ristretto Put under load RPS 200k HitRate 10", benchCache("Put", createRistretto(t), 200000, 10))
 	t.Run("Ristretto Put under load RPS 400k HitRate 10", benchCache("Put", createRistretto(t), 400000, 10))
 
+	t.Run("Sharded LRU Get under load RPS 200k HitRate 90", benchCache("Get", createShardedLRU(t), 200000, 90))
+	t.Run("Sharded LRU Get under load RPS 400k HitRate 90", benchCache("Get", createShardedLRU(t), 400000, 90))
+	t.Run("Sharded LRU Get under load RPS 200k HitRate 10", benchCache("Get", createShardedLRU(t), 200000, 10))
+	t.Run("Sharded LRU Get under load RPS 400k HitRate 10", benchCache("Get", createShardedLRU(t), 400000, 10))
+	t.Run("Sharded LRU Put under load RPS 200k HitRate 90", benchCache("Put", createShardedLRU(t), 200000, 90))
+	t.Run("Sharded LRU Put under load RPS 400k HitRate 90", benchCache("Put", createShardedLRU(t), 400000, 90))
+	t.Run("Sharded LRU Put under load RPS 200k HitRate 10", benchCache("Put", createShardedLRU(t), 200000, 10))
+	t.Run("Sharded LRU Put under load RPS 400k HitRate 10", benchCache("Put", createShardedLRU(t), 400000, 10))
+
+	t.Run("SIEVE Get under load RPS 200k HitRate 90", benchCache("Get", createSIEVE(t), 200000, 90))
+	t.Run("SIEVE Get under load RPS 400k HitRate 90", benchCache("Get", createSIEVE(t), 400000, 90))
+	t.Run("SIEVE Get under load RPS 200k HitRate 10", benchCache("Get", createSIEVE(t), 200000, 10))
+	t.Run("SIEVE Get under load RPS 400k HitRate 10", benchCache("Get", createSIEVE(t), 400000, 10))
+	t.Run("SIEVE Put under load RPS 200k HitRate 90", benchCache("Put", createSIEVE(t), 200000, 90))
+	t.Run("SIEVE Put under load RPS 400k HitRate 90", benchCache("Put", createSIEVE(t), 400000, 90))
+	t.Run("SIEVE Put under load RPS 200k HitRate 10", benchCache("Put", createSIEVE(t), 200000, 10))
+	t.Run("SIEVE Put under load RPS 400k HitRate 10", benchCache("Put", createSIEVE(t), 400000, 10))
+
 	// t.Run("Realtime with LRU engine Get under load RPS 200k HitRate 90", benchCache("Get", createRealtime(t, realtime.WithEngine(realtime.EngineLRU)), 200000, 90))
 	// t.Run("Realtime with LRU engine Get under load RPS 400k HitRate 90", benchCache("Get", createRealtime(t, realtime.WithEngine(realtime.EngineLRU)), 400000, 90))
 	// t.Run("Realtime with LRU engine Get under load RPS 200k HitRate 10", benchCache("Get", createRealtime(t, realtime.WithEngine(realtime.EngineLRU)), 200000, 10))
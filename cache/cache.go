@@ -2,6 +2,7 @@ package cache
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -10,6 +11,18 @@ var (
 	ErrWrongTTL = errors.New("wrong TTL, it should be >= 0")
 	// ErrWrongCapacity is the capacity error if it is less than 0.
 	ErrWrongCapacity = errors.New("wrong capacity, it should be positive")
+	// ErrWrongCost is the cost budget error if it is less than 0.
+	ErrWrongCost = errors.New("wrong max cost, it should be >= 0")
+	// ErrWrongPinnedCap is the pinned segment capacity error if it is less than 0.
+	ErrWrongPinnedCap = errors.New("wrong pinned capacity, it should be >= 0")
+	// ErrPinnedFull is the error of PutPinned if the pinned segment of the cache is full.
+	ErrPinnedFull = errors.New("pinned segment is full")
+	// ErrVersionMismatch is the error of PutIfVersion if the entry was replaced or removed since it was read,
+	// it matches every *VersionMismatchError.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrLeaseInvalid is the error of PutWithLease if the lease was revoked by a write or a removal of the key
+	// (also one racing with the put), consumed by another put or expired.
+	ErrLeaseInvalid = errors.New("lease is invalid")
 )
 
 // Cache is the common interface for all types of caches.
@@ -62,3 +75,181 @@ type WithTTLPutter interface {
 type KeysGetter interface {
 	Keys() []interface{}
 }
+
+// CostPutter is an interface for putting a value into the cache with a specified cost
+// (usually the size of the value in bytes), used by the caches with a cost budget instead of an item count capacity.
+type CostPutter interface {
+	PutWithCost(key, value interface{}, cost int64)
+}
+
+// SoftTTLPutter is an interface for putting a value into the cache with soft and hard TTLs.
+// After the soft TTL the entry is stale: Get treats it as expired, but GetStale still returns it until the hard TTL.
+type SoftTTLPutter interface {
+	PutWithSoftTTL(key, value interface{}, softTTL, hardTTL time.Duration)
+}
+
+// StaleGetter is an interface for cache implementations that can return stale entries (see SoftTTLPutter).
+type StaleGetter interface {
+	// GetStale returns the value for the given key like Get, including the entries which soft TTL has passed,
+	// stale reports whether the soft TTL of the entry has passed.
+	GetStale(key interface{}) (value interface{}, stale bool, ok bool)
+}
+
+// TTLGetter is an interface for getting the default TTL of a cache.
+type TTLGetter interface {
+	TTL() time.Duration
+}
+
+// ComputeTimePutter is an interface for putting a value into the cache with the time it took to compute the value,
+// used by the probabilistic early expiration (see WithXFetch).
+type ComputeTimePutter interface {
+	PutWithComputeTime(key, value interface{}, ttl, computeTime time.Duration)
+}
+
+// ComputeTimeGetter is an interface for cache implementations that can return the expiration and the compute time of the entries.
+type ComputeTimeGetter interface {
+	// GetWithComputeTime returns the value for the given key like Get, with its expiration time (zero if the entry has no TTL)
+	// and the time it took to compute the value (see ComputeTimePutter).
+	GetWithComputeTime(key interface{}) (value interface{}, expires time.Time, computeTime time.Duration, ok bool)
+}
+
+// SlidingTTLPutter is an interface for putting a value into the cache with a sliding or a fixed TTL,
+// regardless of the TTL mode of the cache.
+type SlidingTTLPutter interface {
+	// PutWithSlidingTTL puts the value which expires after ttl of inactivity, every Get extends its expiration.
+	PutWithSlidingTTL(key, value interface{}, ttl time.Duration)
+	// PutWithFixedTTL puts the value which expires after ttl since the write.
+	PutWithFixedTTL(key, value interface{}, ttl time.Duration)
+}
+
+// EntryMeta is the metadata of a cache entry (see MetaGetter).
+type EntryMeta struct {
+	Value interface{}
+	// Inserted is the time the entry was put into the cache.
+	Inserted time.Time
+	// Expires is the expiration time of the entry, zero if the entry has no TTL.
+	Expires time.Time
+	// Accessed is the time of the last read of the entry, or the time it was put if it has not been read.
+	Accessed time.Time
+	// Hits is the number of the reads of the entry since it was put.
+	Hits uint64
+}
+
+// Remaining returns the time left until the entry expires at now, 0 if the entry has no TTL.
+func (m EntryMeta) Remaining(now time.Time) time.Duration {
+	if m.Expires.IsZero() {
+		return 0
+	}
+	if left := m.Expires.Sub(now); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// MetaGetter is an interface for cache implementations that can return the metadata of the entries.
+type MetaGetter interface {
+	// GetWithMeta returns the value for the given key like Get, with the metadata of the entry.
+	// The read is counted in the returned Hits and Accessed.
+	GetWithMeta(key interface{}) (meta EntryMeta, ok bool)
+	// PeekMeta returns the metadata of the entry like Peek, without counting it as a read.
+	PeekMeta(key interface{}) (meta EntryMeta, ok bool)
+}
+
+// KeyValue is a key-value pair of the batch operations (see BatchPutter).
+type KeyValue struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// BatchGetter is an interface for getting many values at once, the metrics are recorded once per batch.
+type BatchGetter interface {
+	// GetMany retrieves the values like Get, it returns the found values by key
+	// and the keys which are missing or expired, in the order of keys.
+	GetMany(keys []interface{}) (hits map[interface{}]interface{}, missing []interface{})
+}
+
+// BatchPutter is an interface for putting and removing many values at once, the metrics are recorded once per batch.
+type BatchPutter interface {
+	// PutMany stores the items like Put, in the order of items.
+	PutMany(items []KeyValue)
+	// RemoveMany removes the values by keys like Remove.
+	RemoveMany(keys []interface{})
+}
+
+// TagPutter is an interface for putting a value into the cache associated with tags (see TagIndex).
+type TagPutter interface {
+	PutWithTags(key, value interface{}, tags ...string)
+}
+
+// TagInvalidator is an interface for removing all entries associated with a tag, it returns the number of removed entries.
+type TagInvalidator interface {
+	InvalidateTag(tag string) int
+}
+
+// PrefixRemover is an interface for removing the entries by their keys, e.g. everything of a user from the interceptor cache.
+type PrefixRemover interface {
+	// RemoveByPrefix removes the entries which keys are strings with the prefix, it returns the number of removed entries.
+	RemoveByPrefix(prefix string) int
+	// RemoveMatching removes the entries which keys match the predicate, it returns the number of removed entries.
+	RemoveMatching(match func(key interface{}) bool) int
+}
+
+// Pinner is an interface for the caches with a pinned segment: the pinned entries are never evicted
+// by the capacity or the cost budget, e.g. the config lookups which must stay in memory.
+// They still expire by TTL and are removed by Remove and Clear.
+type Pinner interface {
+	// PutPinned puts the pinned value with the default TTL, it returns ErrPinnedFull if the pinned segment is full.
+	PutPinned(key, value interface{}) error
+	// Unpin moves the pinned entry to the regular ones, it reports whether the key was pinned.
+	Unpin(key interface{}) bool
+}
+
+// AtomicUpdater is an interface for the atomic read-modify-write operations on a key, e.g. the counters held in the cache.
+// The expired and stale entries are treated as absent, the written values get the default TTL of the cache, like Put.
+type AtomicUpdater interface {
+	// PutIfAbsent puts the value if the key is absent, it returns the current value of the key and whether it was loaded.
+	PutIfAbsent(key, value interface{}) (actual interface{}, loaded bool)
+	// CompareAndSwap replaces the value of the key with newValue if it equals oldValue, it reports whether it was swapped.
+	CompareAndSwap(key, oldValue, newValue interface{}) bool
+	// Update puts the value returned by fn for the current value of the key if keep is true, otherwise it removes the key.
+	// It returns the new value and keep.
+	Update(key interface{}, fn func(old interface{}, exists bool) (value interface{}, keep bool)) (value interface{}, ok bool)
+}
+
+// Versioner is an interface for the optimistic concurrency on the entries: every write of a key gives the entry a new version,
+// greater than the versions of all the previous writes of the cache, so a replaced entry never gets its old version back.
+// The absent, expired and stale keys have version 0.
+type Versioner interface {
+	// GetVersioned returns the value for the key like Get, with the version of the entry.
+	GetVersioned(key interface{}) (value interface{}, version uint64, ok bool)
+	// PutIfVersion puts the value with the default TTL if the version of the key is expectedVersion (0 for an absent key),
+	// otherwise it returns *VersionMismatchError.
+	PutIfVersion(key, value interface{}, expectedVersion uint64) error
+}
+
+// Leaser is an interface for the memcache-style leases, which prevent the stale sets after an invalidation:
+// a miss gives a lease on the key, and the value loaded after the miss is put only if the key
+// was not written or removed since the lease was given.
+type Leaser interface {
+	// GetWithLease returns the value for the key like Get, or a lease token on a miss.
+	GetWithLease(key interface{}) (value interface{}, lease uint64, ok bool)
+	// PutWithLease puts the value if the lease of the key is valid and consumes the lease,
+	// otherwise it returns ErrLeaseInvalid.
+	PutWithLease(key, value interface{}, lease uint64) error
+}
+
+// VersionMismatchError is the error of PutIfVersion with the expected and the actual version of the key.
+type VersionMismatchError struct {
+	Key      interface{}
+	Expected uint64
+	Actual   uint64
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("%s for key %v: expected %d, actual %d", ErrVersionMismatch, e.Key, e.Expected, e.Actual)
+}
+
+// Is makes the error match ErrVersionMismatch.
+func (e *VersionMismatchError) Is(target error) bool {
+	return target == ErrVersionMismatch
+}
@@ -0,0 +1,73 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("advance", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := cache.NewFakeClock(start)
+
+		// act
+		clock.Advance(time.Minute)
+
+		// assert
+		assert.Equal(t, start.Add(time.Minute), clock.Now())
+		assert.Equal(t, time.Minute, clock.Since(start))
+	})
+
+	t.Run("after fires when deadline passes", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := cache.NewFakeClock(start)
+		ch := clock.After(time.Minute)
+
+		// act
+		clock.Advance(time.Minute - time.Nanosecond)
+		firedEarly := len(ch) > 0
+		clock.Advance(time.Nanosecond)
+
+		// assert
+		assert.False(t, firedEarly)
+		assert.Equal(t, start.Add(time.Minute), <-ch)
+		assert.Equal(t, 0, clock.Waiters())
+	})
+
+	t.Run("after without duration fires at once", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := cache.NewFakeClock(start)
+
+		// act
+		ch := clock.After(0)
+
+		// assert
+		assert.Equal(t, start, <-ch)
+		assert.Equal(t, 0, clock.Waiters())
+	})
+
+	t.Run("set", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := cache.NewFakeClock(start)
+		ch := clock.After(time.Hour)
+
+		// act
+		clock.Set(start.Add(2 * time.Hour))
+
+		// assert
+		assert.Equal(t, start.Add(2*time.Hour), <-ch)
+	})
+}
@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWrongBatchSize is the max batch size error if it is less than 0.
+	ErrWrongBatchSize = errors.New("wrong max batch size, it should be >= 0")
+	// ErrWrongBatchWait is the batch window error if it is not positive.
+	ErrWrongBatchWait = errors.New("wrong batch wait, it should be positive")
+	// ErrWrongBatchResult is the error of all keys of a batch if BatchLoadFunc returns a wrong number of results.
+	ErrWrongBatchResult = errors.New("wrong number of batch results")
+)
+
+const defaultBatchWait = time.Millisecond
+
+// Result is the result of loading a key.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// BatchLoadFunc loads the values for the keys from the source of truth at once.
+// It returns a result for every key in the order of keys, the error fails the whole batch.
+// The failed results are not cached.
+type BatchLoadFunc func(ctx context.Context, keys []interface{}) ([]Result, error)
+
+// DataLoaderOption is an option of the DataLoader.
+type DataLoaderOption func(*DataLoader)
+
+// WithBatchWait sets the window in which the loads are collected into a batch, 1ms by default.
+func WithBatchWait(wait time.Duration) DataLoaderOption {
+	return func(l *DataLoader) {
+		l.wait = wait
+	}
+}
+
+// WithMaxBatch limits the number of keys in a batch, a full batch is loaded without waiting for the window.
+// By default (0) the size of the batches is not limited.
+func WithMaxBatch(n int) DataLoaderOption {
+	return func(l *DataLoader) {
+		l.maxBatch = n
+	}
+}
+
+// WithDataLoaderClock sets the clock of the batch window, e.g. a FakeClock for deterministic tests.
+// SystemClock is used by default.
+func WithDataLoaderClock(clock Clock) DataLoaderOption {
+	return func(l *DataLoader) {
+		l.clock = clock
+	}
+}
+
+// DataLoader is a batching read-through wrapper over a cache: the cache misses of the individual loads
+// are collected over a short window (or up to the max batch size) and loaded with a single BatchLoadFunc call,
+// which solves the N+1 problem of the fan-out code. The same key is loaded once per batch.
+type DataLoader struct {
+	cache    Cache
+	load     BatchLoadFunc
+	wait     time.Duration
+	maxBatch int
+	clock    Clock
+
+	mu sync.Mutex
+	// batch is the batch collecting the keys, nil if there is none
+	batch *dataBatch
+}
+
+// dataBatch is a batch of keys to load, full is closed when the batch reaches the max batch size.
+type dataBatch struct {
+	keys  []interface{}
+	calls map[interface{}]*loadCall
+	full  chan struct{}
+}
+
+// NewDataLoader creates a DataLoader over the cache c, which loads the missing values with load.
+func NewDataLoader(c Cache, load BatchLoadFunc, opts ...DataLoaderOption) (*DataLoader, error) {
+	var name string
+	if named, ok := c.(Named); ok {
+		name = named.Name()
+	}
+
+	l := &DataLoader{
+		cache: c,
+		load:  load,
+		wait:  defaultBatchWait,
+		clock: SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.wait <= 0 {
+		return nil, fmt.Errorf("can't create data loader %s: %w", name, ErrWrongBatchWait)
+	}
+	if l.maxBatch < 0 {
+		return nil, fmt.Errorf("can't create data loader %s: %w", name, ErrWrongBatchSize)
+	}
+
+	return l, nil
+}
+
+// Load returns the value for the key from the cache, or adds the key to the current batch and waits for it.
+// The batch is loaded with the context of its first key, not canceled with it.
+// If ctx is canceled while waiting, Load returns its error, the batch is loaded anyway.
+func (l *DataLoader) Load(ctx context.Context, key interface{}) (interface{}, error) {
+	if v, ok := l.cache.Get(key); ok {
+		return v, nil
+	}
+
+	return waitCall(ctx, l.enqueue(ctx, key))
+}
+
+// LoadMany returns the results for the keys in the order of keys, the cache hits are served immediately
+// and the misses are loaded in the current batch (see Load).
+func (l *DataLoader) LoadMany(ctx context.Context, keys []interface{}) []Result {
+	results := make([]Result, len(keys))
+	calls := make([]*loadCall, len(keys))
+
+	if getter, ok := l.cache.(BatchGetter); ok {
+		hits, _ := getter.GetMany(keys)
+		for i, key := range keys {
+			if v, ok := hits[key]; ok {
+				results[i].Value = v
+				continue
+			}
+			calls[i] = l.enqueue(ctx, key)
+		}
+	} else {
+		for i, key := range keys {
+			if v, ok := l.cache.Get(key); ok {
+				results[i].Value = v
+				continue
+			}
+			calls[i] = l.enqueue(ctx, key)
+		}
+	}
+
+	for i, c := range calls {
+		if c != nil {
+			results[i].Value, results[i].Err = waitCall(ctx, c)
+		}
+	}
+	return results
+}
+
+// enqueue adds the key to the current batch, starting a new one if there is none.
+func (l *DataLoader) enqueue(ctx context.Context, key interface{}) *loadCall {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.batch
+	if b == nil {
+		b = &dataBatch{calls: make(map[interface{}]*loadCall), full: make(chan struct{})}
+		l.batch = b
+		go l.run(context.WithoutCancel(ctx), b)
+	}
+	if c, ok := b.calls[key]; ok {
+		return c
+	}
+
+	c := &loadCall{done: make(chan struct{})}
+	b.calls[key] = c
+	b.keys = append(b.keys, key)
+	if l.maxBatch > 0 && len(b.keys) >= l.maxBatch {
+		l.batch = nil
+		close(b.full)
+	}
+	return c
+}
+
+// run loads the batch after the window or once it is full, and puts the loaded values into the cache.
+func (l *DataLoader) run(ctx context.Context, b *dataBatch) {
+	select {
+	case <-l.clock.After(l.wait):
+	case <-b.full:
+	}
+
+	l.mu.Lock()
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	results, err := l.load(ctx, b.keys)
+	if err == nil && len(results) != len(b.keys) {
+		err = fmt.Errorf("%w: %d results for %d keys", ErrWrongBatchResult, len(results), len(b.keys))
+	}
+
+	items := make([]KeyValue, 0, len(b.keys))
+	for i, key := range b.keys {
+		c := b.calls[key]
+		if err != nil {
+			c.err = err
+			continue
+		}
+		c.value, c.err = results[i].Value, results[i].Err
+		if c.err == nil {
+			items = append(items, KeyValue{Key: key, Value: c.value})
+		}
+	}
+
+	if putter, ok := l.cache.(BatchPutter); ok {
+		putter.PutMany(items)
+	} else {
+		for _, item := range items {
+			l.cache.Put(item.Key, item.Value)
+		}
+	}
+
+	for _, c := range b.calls {
+		close(c.done)
+	}
+}
+
+// waitCall waits for the load c to finish or ctx to be canceled.
+func waitCall(ctx context.Context, c *loadCall) (interface{}, error) {
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,183 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataLoader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("full batch", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c.Put(4, "cached")
+
+		var mu sync.Mutex
+		var batches [][]interface{}
+		l, err := cache.NewDataLoader(c, func(ctx context.Context, keys []interface{}) ([]cache.Result, error) {
+			mu.Lock()
+			batches = append(batches, keys)
+			mu.Unlock()
+			results := make([]cache.Result, len(keys))
+			for i, key := range keys {
+				results[i].Value = key.(int) * 10
+			}
+			return results, nil
+		}, cache.WithBatchWait(time.Hour), cache.WithMaxBatch(3))
+		require.NoError(t, err)
+
+		// act
+		results := l.LoadMany(context.Background(), []interface{}{1, 2, 2, 4, 3})
+
+		// assert
+		assert.Equal(t, []cache.Result{{Value: 10}, {Value: 20}, {Value: 20}, {Value: "cached"}, {Value: 30}}, results)
+		assert.Equal(t, [][]interface{}{{1, 2, 3}}, batches)
+		v, ok := c.Get(2)
+		assert.True(t, ok)
+		assert.Equal(t, 20, v)
+	})
+
+	t.Run("batch window", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		clock := cache.NewFakeClock(time.Now())
+		l, err := cache.NewDataLoader(c, func(ctx context.Context, keys []interface{}) ([]cache.Result, error) {
+			return []cache.Result{{Value: "value"}}, nil
+		}, cache.WithBatchWait(time.Second), cache.WithDataLoaderClock(clock))
+		require.NoError(t, err)
+
+		type result struct {
+			value interface{}
+			err   error
+		}
+		done := make(chan result, 1)
+		go func() {
+			v, err := l.Load(context.Background(), "key")
+			done <- result{value: v, err: err}
+		}()
+		require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+
+		// act
+		clock.Advance(time.Second)
+		r := <-done
+
+		// assert
+		require.NoError(t, r.err)
+		assert.Equal(t, "value", r.value)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		notFound := errors.New("not found")
+		l, err := cache.NewDataLoader(c, func(ctx context.Context, keys []interface{}) ([]cache.Result, error) {
+			return []cache.Result{{Value: "a"}, {Err: notFound}}, nil
+		}, cache.WithMaxBatch(2))
+		require.NoError(t, err)
+
+		// act
+		results := l.LoadMany(context.Background(), []interface{}{"a", "b"})
+
+		// assert
+		assert.Equal(t, []cache.Result{{Value: "a"}, {Err: notFound}}, results)
+		assert.True(t, c.Contains("a"))
+		assert.False(t, c.Contains("b"))
+	})
+
+	t.Run("batch error", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		loadErr := errors.New("load failed")
+		l, err := cache.NewDataLoader(c, func(ctx context.Context, keys []interface{}) ([]cache.Result, error) {
+			return nil, loadErr
+		}, cache.WithMaxBatch(2))
+		require.NoError(t, err)
+
+		// act
+		results := l.LoadMany(context.Background(), []interface{}{"a", "b"})
+
+		// assert
+		for _, r := range results {
+			assert.ErrorIs(t, r.Err, loadErr)
+		}
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("wrong number of results", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		l, err := cache.NewDataLoader(c, func(ctx context.Context, keys []interface{}) ([]cache.Result, error) {
+			return []cache.Result{{Value: "a"}}, nil
+		}, cache.WithMaxBatch(2))
+		require.NoError(t, err)
+
+		// act
+		results := l.LoadMany(context.Background(), []interface{}{"a", "b"})
+
+		// assert
+		for _, r := range results {
+			assert.ErrorIs(t, r.Err, cache.ErrWrongBatchResult)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		l, err := cache.NewDataLoader(c, func(ctx context.Context, keys []interface{}) ([]cache.Result, error) {
+			return []cache.Result{{Value: "a"}}, nil
+		}, cache.WithBatchWait(time.Hour))
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// act
+		_, err = l.Load(ctx, "a")
+
+		// assert
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("wrong options", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		load := func(ctx context.Context, keys []interface{}) ([]cache.Result, error) {
+			return nil, nil
+		}
+
+		// act
+		_, waitErr := cache.NewDataLoader(c, load, cache.WithBatchWait(0))
+		_, sizeErr := cache.NewDataLoader(c, load, cache.WithMaxBatch(-1))
+
+		// assert
+		require.ErrorIs(t, waitErr, cache.ErrWrongBatchWait)
+		require.ErrorIs(t, sizeErr, cache.ErrWrongBatchSize)
+	})
+}
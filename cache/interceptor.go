@@ -3,20 +3,85 @@ package cache
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/catalystgo/cache-go/cache/metrics"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 )
 
+// StaleHeader is the response header set to "true" when the interceptor serves a stale response (see WithInterceptorStaleIfError).
+const StaleHeader = "x-cache-stale"
+
+// InterceptorOption is an option of the gRPC interceptor.
+type InterceptorOption func(*interceptor)
+
+// WithInterceptorStaleIfError keeps the expired responses for the extra window and returns them when the handler fails,
+// optionally only for the errors with the given gRPC codes (e.g. codes.Unavailable).
+// The stale responses are marked with StaleHeader. The responses are fresh for the default TTL of the cache (see TTLGetter).
+// It applies only to the caches implementing SoftTTLPutter and StaleGetter, the other caches work as usual.
+func WithInterceptorStaleIfError(window time.Duration, errCodes ...codes.Code) InterceptorOption {
+	return func(i *interceptor) {
+		i.staleIfError = &staleIfError{window: window, codes: errCodes}
+	}
+}
+
+// WithInterceptorXFetch enables the probabilistic early expiration (see WithXFetch) of the cached responses:
+// a request may call the handler before the response expires, with the probability growing as the expiration approaches
+// and with the time the handler took. If such call fails, the cached response is returned.
+// It applies only to the caches implementing ComputeTimePutter and ComputeTimeGetter, the other caches work as usual.
+// The caches served with WithInterceptorStaleIfError do not use XFetch.
+func WithInterceptorXFetch(beta float64) InterceptorOption {
+	return func(i *interceptor) {
+		i.xfetchBeta = beta
+	}
+}
+
+// WithInterceptorClock sets the clock of the XFetch expiration checks and of the measured handler times,
+// e.g. a FakeClock for deterministic tests. It should match the clock of the caches. SystemClock is used by default.
+func WithInterceptorClock(clock Clock) InterceptorOption {
+	return func(i *interceptor) {
+		i.clock = clock
+	}
+}
+
 // NewInterceptor creates an interceptor for use with gRPC.
-func NewInterceptor(registry Registry) grpc.UnaryServerInterceptor {
-	return interceptor{registry: registry}.unaryServer
+func NewInterceptor(registry Registry, opts ...InterceptorOption) grpc.UnaryServerInterceptor {
+	i := &interceptor{registry: registry, clock: SystemClock}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i.unaryServer
 }
 
 type interceptor struct {
-	registry Registry
+	registry     Registry
+	clock        Clock
+	staleIfError *staleIfError
+	// xfetchBeta enables XFetch if positive
+	xfetchBeta float64
+
+	// metrics of the caches by name, used to count the stale responses
+	metrics sync.Map
+}
+
+// staleCache is a cache which can keep the stale responses.
+type staleCache interface {
+	NamedCache
+	SoftTTLPutter
+	StaleGetter
+}
+
+// xfetchCache is a cache which keeps the compute time of the responses.
+type xfetchCache interface {
+	NamedCache
+	ComputeTimePutter
+	ComputeTimeGetter
 }
 
-func (i interceptor) unaryServer(
+func (i *interceptor) unaryServer(
 	ctx context.Context,
 	request interface{},
 	info *grpc.UnaryServerInfo,
@@ -33,6 +98,22 @@ func (i interceptor) unaryServer(
 
 	key := stringer.String()
 
+	if i.staleIfError != nil {
+		if sc, ok := cache.(staleCache); ok {
+			return i.serveStaleIfError(ctx, request, key, sc, handler)
+		}
+	}
+
+	if i.xfetchBeta > 0 {
+		if xc, ok := cache.(xfetchCache); ok {
+			return i.serveXFetch(ctx, request, key, xc, handler)
+		}
+	}
+
+	if leaser, ok := cache.(Leaser); ok {
+		return i.serveLeased(ctx, request, key, leaser, handler)
+	}
+
 	value, ok := cache.Get(key)
 	if ok {
 		return value, nil
@@ -45,3 +126,97 @@ func (i interceptor) unaryServer(
 
 	return response, err
 }
+
+// serveLeased serves the request from a Leaser, the response is put only if the key
+// was not written or removed while the handler was running.
+func (i *interceptor) serveLeased(
+	ctx context.Context,
+	request interface{},
+	key string,
+	leaser Leaser,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	value, lease, ok := leaser.GetWithLease(key)
+	if ok {
+		return value, nil
+	}
+
+	response, err := handler(ctx, request)
+	if err == nil {
+		_ = leaser.PutWithLease(key, response, lease)
+	}
+
+	return response, err
+}
+
+func (i *interceptor) serveStaleIfError(
+	ctx context.Context,
+	request interface{},
+	key string,
+	cache staleCache,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	value, stale, ok := cache.GetStale(key)
+	if ok && !stale {
+		return value, nil
+	}
+
+	response, err := handler(ctx, request)
+	if err == nil {
+		var ttl time.Duration
+		if ttlGetter, ok := cache.(TTLGetter); ok {
+			ttl = ttlGetter.TTL()
+		}
+		if ttl > 0 {
+			cache.PutWithSoftTTL(key, response, ttl, ttl+i.staleIfError.window)
+		} else {
+			cache.Put(key, response)
+		}
+		return response, nil
+	}
+	if !ok || !i.staleIfError.match(err) {
+		return response, err
+	}
+
+	// the header can't be set outside of a gRPC server stream, the response is served anyway
+	_ = grpc.SetHeader(ctx, metadata.Pairs(StaleHeader, "true"))
+	i.cacheMetrics(cache.Name()).StaleOnErrorCount.Inc()
+
+	return value, nil
+}
+
+func (i *interceptor) serveXFetch(
+	ctx context.Context,
+	request interface{},
+	key string,
+	cache xfetchCache,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	value, expires, computeTime, ok := cache.GetWithComputeTime(key)
+	if ok && !xfetch(i.clock.Now(), expires, computeTime, i.xfetchBeta) {
+		return value, nil
+	}
+
+	start := i.clock.Now()
+	response, err := handler(ctx, request)
+	if err != nil {
+		if ok {
+			// the cached response has not expired yet
+			return value, nil
+		}
+		return response, err
+	}
+
+	var ttl time.Duration
+	if ttlGetter, ok := cache.(TTLGetter); ok {
+		ttl = ttlGetter.TTL()
+	}
+	cache.PutWithComputeTime(key, response, ttl, i.clock.Since(start))
+
+	return response, nil
+}
+
+func (i *interceptor) cacheMetrics(name string) *metrics.CacheMetrics {
+	if m, ok := i.metrics.Load(name); ok {
+		return m.(*metrics.CacheMetrics)
+	}
+	m, _ := i.metrics.LoadOrStore(name, metrics.NewCacheMetrics(name))
+	return m.(*metrics.CacheMetrics)
+}
@@ -3,13 +3,18 @@ package cache_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/lru"
 	"github.com/catalystgo/cache-go/mock"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type testRequest string
@@ -137,3 +142,253 @@ func TestInterceptor(t *testing.T) {
 		})
 	}
 }
+
+// testServerStream captures the headers set by the interceptor.
+type testServerStream struct {
+	header metadata.MD
+}
+
+func (s *testServerStream) Method() string { return "" }
+
+func (s *testServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *testServerStream) SendHeader(md metadata.MD) error { return nil }
+
+func (s *testServerStream) SetTrailer(md metadata.MD) error { return nil }
+
+func TestInterceptor_StaleIfError(t *testing.T) {
+	t.Parallel()
+
+	const testMethodName = "my-test-method"
+	const testResponse = "my-test-response"
+
+	unavailable := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "backend is down")
+	}
+
+	cases := []struct {
+		name    string
+		wait    time.Duration
+		handler grpc.UnaryHandler
+		assert  func(resp interface{}, err error, stream *testServerStream)
+	}{
+		{
+			name:    "fresh response",
+			handler: unavailable,
+			assert: func(resp interface{}, err error, stream *testServerStream) {
+				require.NoError(t, err)
+				require.Equal(t, testResponse, resp)
+				require.Empty(t, stream.header.Get(cache.StaleHeader))
+			},
+		},
+		{
+			name:    "stale response on matching error",
+			wait:    30 * time.Millisecond,
+			handler: unavailable,
+			assert: func(resp interface{}, err error, stream *testServerStream) {
+				require.NoError(t, err)
+				require.Equal(t, testResponse, resp)
+				require.Equal(t, []string{"true"}, stream.header.Get(cache.StaleHeader))
+			},
+		},
+		{
+			name: "error with other code",
+			wait: 30 * time.Millisecond,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, status.Error(codes.InvalidArgument, "bad request")
+			},
+			assert: func(resp interface{}, err error, stream *testServerStream) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+				require.Nil(t, resp)
+			},
+		},
+		{
+			name:    "error after stale window",
+			wait:    250 * time.Millisecond,
+			handler: unavailable,
+			assert: func(resp interface{}, err error, stream *testServerStream) {
+				require.Equal(t, codes.Unavailable, status.Code(err))
+				require.Nil(t, resp)
+			},
+		},
+		{
+			name: "refreshed on success",
+			wait: 30 * time.Millisecond,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "my-new-response", nil
+			},
+			assert: func(resp interface{}, err error, stream *testServerStream) {
+				require.NoError(t, err)
+				require.Equal(t, "my-new-response", resp)
+				require.Empty(t, stream.header.Get(cache.StaleHeader))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := lru.NewCache(testMethodName, 10, 20*time.Millisecond)
+			require.NoError(t, err)
+			registry := cache.NewRegistry()
+			require.NoError(t, registry.Register(c))
+
+			intercept := cache.NewInterceptor(registry, cache.WithInterceptorStaleIfError(200*time.Millisecond, codes.Unavailable))
+			serverInfo := &grpc.UnaryServerInfo{FullMethod: testMethodName}
+			_, err = intercept(context.Background(), testRequest("my-test-request"), serverInfo,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					return testResponse, nil
+				})
+			require.NoError(t, err)
+			time.Sleep(tc.wait)
+
+			stream := &testServerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+			// act
+			resp, err := intercept(ctx, testRequest("my-test-request"), serverInfo, tc.handler)
+
+			// assert
+			tc.assert(resp, err, stream)
+		})
+	}
+}
+
+func TestInterceptor_XFetch(t *testing.T) {
+	t.Parallel()
+
+	const testMethodName = "my-test-method"
+
+	cases := []struct {
+		name     string
+		beta     float64
+		handler  grpc.UnaryHandler
+		expected interface{}
+	}{
+		{
+			name: "far from expiration",
+			beta: 1e-9,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				require.FailNow(t, "should not be called")
+				return nil, nil
+			},
+			expected: "my-test-response",
+		},
+		{
+			name: "recomputed early",
+			beta: 1e9,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "my-new-response", nil
+			},
+			expected: "my-new-response",
+		},
+		{
+			name: "early recompute failed",
+			beta: 1e9,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, status.Error(codes.Unavailable, "backend is down")
+			},
+			expected: "my-test-response",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := lru.NewCache(testMethodName, 10, time.Minute)
+			require.NoError(t, err)
+			registry := cache.NewRegistry()
+			require.NoError(t, registry.Register(c))
+
+			intercept := cache.NewInterceptor(registry, cache.WithInterceptorXFetch(tc.beta))
+			serverInfo := &grpc.UnaryServerInfo{FullMethod: testMethodName}
+			_, err = intercept(context.Background(), testRequest("my-test-request"), serverInfo,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					time.Sleep(time.Millisecond)
+					return "my-test-response", nil
+				})
+			require.NoError(t, err)
+
+			// act
+			resp, err := intercept(context.Background(), testRequest("my-test-request"), serverInfo, tc.handler)
+
+			// assert
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, resp)
+		})
+	}
+}
+
+func TestInterceptor_XFetchClock(t *testing.T) {
+	t.Parallel()
+
+	const testMethodName = "my-test-method"
+
+	clock := cache.NewFakeClock(time.Now())
+	c, err := lru.NewCache(testMethodName, 10, time.Minute, lru.WithClock(clock))
+	require.NoError(t, err)
+	registry := cache.NewRegistry()
+	require.NoError(t, registry.Register(c))
+	intercept := cache.NewInterceptor(registry, cache.WithInterceptorXFetch(1), cache.WithInterceptorClock(clock))
+	serverInfo := &grpc.UnaryServerInfo{FullMethod: testMethodName}
+
+	// act
+	_, err = intercept(context.Background(), testRequest("my-test-request"), serverInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			clock.Advance(10 * time.Second)
+			return "my-test-response", nil
+		})
+
+	// assert
+	require.NoError(t, err)
+	_, expires, computeTime, ok := c.GetWithComputeTime("my-test-request")
+	require.True(t, ok)
+	assert.Equal(t, 10*time.Second, computeTime)
+	assert.Equal(t, clock.Now().Add(time.Minute), expires)
+}
+
+func TestInterceptor_Lease(t *testing.T) {
+	t.Parallel()
+
+	const testMethodName = "my-test-method"
+
+	base, err := lru.NewCache(testMethodName, 10, 0)
+	require.NoError(t, err)
+	c, err := cache.NewLeaseCache(base)
+	require.NoError(t, err)
+	registry := cache.NewRegistry()
+	require.NoError(t, registry.Register(c))
+
+	intercept := cache.NewInterceptor(registry)
+	serverInfo := &grpc.UnaryServerInfo{FullMethod: testMethodName}
+
+	// act
+	staleResp, staleErr := intercept(context.Background(), testRequest("my-test-request"), serverInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			// the response is invalidated while it is computed
+			c.Remove("my-test-request")
+			return "my-stale-response", nil
+		})
+	containsStale := c.Contains("my-test-request")
+	resp, err := intercept(context.Background(), testRequest("my-test-request"), serverInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "my-test-response", nil
+		})
+
+	// assert
+	require.NoError(t, staleErr)
+	require.Equal(t, "my-stale-response", staleResp)
+	require.False(t, containsStale)
+	require.NoError(t, err)
+	require.Equal(t, "my-test-response", resp)
+	v, ok := c.Get("my-test-request")
+	require.True(t, ok)
+	require.Equal(t, "my-test-response", v)
+}
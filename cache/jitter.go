@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrWrongJitter is the TTL jitter error if it is less than 0.
+var ErrWrongJitter = errors.New("wrong TTL jitter, it should be >= 0")
+
+// Jitter randomizes the TTLs, so the entries put at the same time (e.g. when a cache is warmed in bulk)
+// do not expire at the same moment. The jitter is added to the TTL, so the entries never expire earlier than requested.
+// A nil *Jitter adds no jitter.
+type Jitter struct {
+	max     time.Duration
+	percent float64
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewJitter creates a Jitter which adds up to max plus up to percent of the TTL (e.g. 0.1 for 10%) to the TTLs.
+// src is the random source, pass a fixed-seed source for deterministic tests, nil means a time-seeded source.
+// It returns nil (no jitter) if both max and percent are 0.
+func NewJitter(max time.Duration, percent float64, src rand.Source) (*Jitter, error) {
+	if max < 0 || percent < 0 {
+		return nil, ErrWrongJitter
+	}
+	if max == 0 && percent == 0 {
+		return nil, nil
+	}
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+
+	return &Jitter{
+		max:     max,
+		percent: percent,
+		rnd:     rand.New(src),
+	}, nil
+}
+
+// Duration returns a random jitter to add to the ttl, it is 0 if ttl <= 0.
+func (j *Jitter) Duration(ttl time.Duration) time.Duration {
+	if j == nil || ttl <= 0 {
+		return 0
+	}
+
+	limit := j.max + time.Duration(float64(ttl)*j.percent)
+	if limit <= 0 {
+		return 0
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return time.Duration(j.rnd.Int63n(int64(limit) + 1))
+}
@@ -0,0 +1,88 @@
+package cache_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJitter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no jitter", func(t *testing.T) {
+		t.Parallel()
+
+		j, err := cache.NewJitter(0, 0, nil)
+		require.NoError(t, err)
+
+		// act
+		d := j.Duration(time.Minute)
+
+		// assert
+		assert.Nil(t, j)
+		assert.Zero(t, d)
+	})
+
+	t.Run("fixed range and percent", func(t *testing.T) {
+		t.Parallel()
+
+		j, err := cache.NewJitter(time.Second, 0.5, rand.NewSource(1))
+		require.NoError(t, err)
+
+		// act
+		var spread []time.Duration
+		for i := 0; i < 100; i++ {
+			spread = append(spread, j.Duration(10*time.Second))
+		}
+
+		// assert
+		for _, d := range spread {
+			assert.GreaterOrEqual(t, d, time.Duration(0))
+			assert.LessOrEqual(t, d, 6*time.Second)
+		}
+		assert.NotEqual(t, spread[0], spread[1])
+	})
+
+	t.Run("deterministic with source", func(t *testing.T) {
+		t.Parallel()
+
+		j1, err := cache.NewJitter(time.Minute, 0, rand.NewSource(42))
+		require.NoError(t, err)
+		j2, err := cache.NewJitter(time.Minute, 0, rand.NewSource(42))
+		require.NoError(t, err)
+
+		// act
+		d1 := []time.Duration{j1.Duration(time.Second), j1.Duration(time.Second)}
+		d2 := []time.Duration{j2.Duration(time.Second), j2.Duration(time.Second)}
+
+		// assert
+		assert.Equal(t, d1, d2)
+	})
+
+	t.Run("no jitter without ttl", func(t *testing.T) {
+		t.Parallel()
+
+		j, err := cache.NewJitter(time.Minute, 0, nil)
+		require.NoError(t, err)
+
+		// act
+		d := j.Duration(0)
+
+		// assert
+		assert.Zero(t, d)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		t.Parallel()
+
+		// act
+		_, err := cache.NewJitter(-time.Second, 0, nil)
+
+		// assert
+		require.ErrorIs(t, err, cache.ErrWrongJitter)
+	})
+}
@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+)
+
+// KeyIndex is a radix tree of the string keys of a cache, which finds the keys with a prefix
+// without scanning all keys (see PrefixRemover). The keys of other types are ignored.
+// A nil *KeyIndex is an empty index which ignores the updates.
+type KeyIndex struct {
+	mu   sync.Mutex
+	root radixNode
+	len  int
+}
+
+// radixNode is a node of the radix tree, the children have non-empty prefixes with distinct first bytes.
+type radixNode struct {
+	prefix   string
+	leaf     bool
+	children []*radixNode
+}
+
+// NewKeyIndex creates an empty KeyIndex.
+func NewKeyIndex() *KeyIndex {
+	return &KeyIndex{}
+}
+
+// Insert adds the key to the index if it is a string.
+func (x *KeyIndex) Insert(key interface{}) {
+	s, ok := key.(string)
+	if x == nil || !ok {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	n := &x.root
+	for {
+		if s == "" {
+			if !n.leaf {
+				n.leaf = true
+				x.len++
+			}
+			return
+		}
+		child := n.child(s[0])
+		if child == nil {
+			n.children = append(n.children, &radixNode{prefix: s, leaf: true})
+			x.len++
+			return
+		}
+		common := commonPrefixLen(s, child.prefix)
+		if common < len(child.prefix) {
+			split := &radixNode{prefix: child.prefix[common:], leaf: child.leaf, children: child.children}
+			child.prefix = child.prefix[:common]
+			child.leaf = false
+			child.children = []*radixNode{split}
+		}
+		s = s[common:]
+		n = child
+	}
+}
+
+// Delete removes the key from the index if it is a string.
+func (x *KeyIndex) Delete(key interface{}) {
+	s, ok := key.(string)
+	if x == nil || !ok {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	var parent *radixNode
+	n := &x.root
+	for s != "" {
+		child := n.child(s[0])
+		if child == nil || !strings.HasPrefix(s, child.prefix) {
+			return
+		}
+		s = s[len(child.prefix):]
+		parent, n = n, child
+	}
+	if !n.leaf {
+		return
+	}
+	n.leaf = false
+	x.len--
+	if parent == nil {
+		return
+	}
+
+	// keep the tree compressed: no empty leaves and no inner nodes with a single child
+	switch len(n.children) {
+	case 0:
+		parent.removeChild(n)
+		if parent != &x.root && !parent.leaf && len(parent.children) == 1 {
+			parent.mergeChild()
+		}
+	case 1:
+		n.mergeChild()
+	}
+}
+
+// KeysWithPrefix returns the keys of the index with the prefix.
+func (x *KeyIndex) KeysWithPrefix(prefix string) []interface{} {
+	if x == nil {
+		return nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	n := &x.root
+	path := ""
+	for prefix != "" {
+		child := n.child(prefix[0])
+		if child == nil {
+			return nil
+		}
+		switch {
+		case strings.HasPrefix(prefix, child.prefix):
+			prefix = prefix[len(child.prefix):]
+		case strings.HasPrefix(child.prefix, prefix):
+			prefix = ""
+		default:
+			return nil
+		}
+		path += child.prefix
+		n = child
+	}
+
+	var keys []interface{}
+	n.collect(path, &keys)
+	return keys
+}
+
+// Len returns the number of the keys in the index.
+func (x *KeyIndex) Len() int {
+	if x == nil {
+		return 0
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.len
+}
+
+// OnEvict returns the evict callback which removes the evicted keys from the index and calls next if it is not nil.
+func (x *KeyIndex) OnEvict(next func(key, value interface{})) func(key, value interface{}) {
+	if x == nil {
+		return next
+	}
+	return func(key, value interface{}) {
+		x.Delete(key)
+		if next != nil {
+			next(key, value)
+		}
+	}
+}
+
+func (n *radixNode) child(b byte) *radixNode {
+	for _, c := range n.children {
+		if c.prefix[0] == b {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *radixNode) removeChild(child *radixNode) {
+	for i, c := range n.children {
+		if c == child {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+			return
+		}
+	}
+}
+
+// mergeChild merges the single child into the node.
+func (n *radixNode) mergeChild() {
+	c := n.children[0]
+	n.prefix += c.prefix
+	n.leaf = c.leaf
+	n.children = c.children
+}
+
+func (n *radixNode) collect(path string, keys *[]interface{}) {
+	if n.leaf {
+		*keys = append(*keys, path)
+	}
+	for _, c := range n.children {
+		c.collect(path+c.prefix, keys)
+	}
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// HasPrefix returns the predicate matching the string keys with the prefix (see PrefixRemover).
+func HasPrefix(prefix string) func(key interface{}) bool {
+	return func(key interface{}) bool {
+		s, ok := key.(string)
+		return ok && strings.HasPrefix(s, prefix)
+	}
+}
+
+// FilterKeys returns the keys matching the predicate.
+func FilterKeys(keys []interface{}, match func(key interface{}) bool) []interface{} {
+	var matched []interface{}
+	for _, key := range keys {
+		if match(key) {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,110 @@
+package cache_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyIndex(t *testing.T) {
+	t.Parallel()
+
+	sorted := func(keys []interface{}) []string {
+		s := make([]string, 0, len(keys))
+		for _, key := range keys {
+			s = append(s, key.(string))
+		}
+		sort.Strings(s)
+		return s
+	}
+
+	t.Run("keys with prefix", func(t *testing.T) {
+		t.Parallel()
+
+		x := cache.NewKeyIndex()
+		for _, key := range []interface{}{"user:42:orders", "user:42", "user:4", "user:420:cart", "item:1", "", 42} {
+			x.Insert(key)
+		}
+
+		// act
+		user42 := x.KeysWithPrefix("user:42")
+		user4 := x.KeysWithPrefix("user:4")
+		partial := x.KeysWithPrefix("use")
+		all := x.KeysWithPrefix("")
+		none := x.KeysWithPrefix("user:5")
+
+		// assert
+		assert.Equal(t, []string{"user:42", "user:420:cart", "user:42:orders"}, sorted(user42))
+		assert.Equal(t, []string{"user:4", "user:42", "user:420:cart", "user:42:orders"}, sorted(user4))
+		assert.Equal(t, sorted(user4), sorted(partial))
+		assert.Equal(t, []string{"", "item:1", "user:4", "user:42", "user:420:cart", "user:42:orders"}, sorted(all))
+		assert.Empty(t, none)
+		assert.Equal(t, 6, x.Len())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		t.Parallel()
+
+		x := cache.NewKeyIndex()
+		for _, key := range []string{"user:42:orders", "user:42", "user:420:cart", "user:43"} {
+			x.Insert(key)
+		}
+
+		// act
+		x.Delete("user:42")
+		x.Delete("user:42:orders")
+		x.Delete("user:4")
+		x.Delete("missing")
+
+		// assert
+		assert.Equal(t, []string{"user:420:cart", "user:43"}, sorted(x.KeysWithPrefix("user:4")))
+		assert.Equal(t, []string{"user:420:cart"}, sorted(x.KeysWithPrefix("user:42")))
+		assert.Equal(t, 2, x.Len())
+	})
+
+	t.Run("insert twice", func(t *testing.T) {
+		t.Parallel()
+
+		x := cache.NewKeyIndex()
+
+		// act
+		x.Insert("a")
+		x.Insert("a")
+
+		// assert
+		assert.Equal(t, 1, x.Len())
+	})
+
+	t.Run("on evict", func(t *testing.T) {
+		t.Parallel()
+
+		x := cache.NewKeyIndex()
+		x.Insert("a")
+		var evicted []interface{}
+
+		// act
+		x.OnEvict(func(key, value interface{}) {
+			evicted = append(evicted, key)
+		})("a", 1)
+
+		// assert
+		assert.Equal(t, []interface{}{"a"}, evicted)
+		assert.Equal(t, 0, x.Len())
+	})
+
+	t.Run("nil index", func(t *testing.T) {
+		t.Parallel()
+
+		var x *cache.KeyIndex
+
+		// act
+		x.Insert("a")
+		x.Delete("a")
+
+		// assert
+		assert.Empty(t, x.KeysWithPrefix(""))
+		assert.Equal(t, 0, x.Len())
+	})
+}
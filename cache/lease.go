@@ -0,0 +1,381 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrWrongLeaseTTL is the lease TTL error if it is not positive.
+var ErrWrongLeaseTTL = errors.New("wrong lease TTL, it should be positive")
+
+const (
+	defaultLeaseTTL = 10 * time.Second
+	// minLeaseSweep is the number of the leases from which the expired ones are swept when a lease is given
+	minLeaseSweep = 64
+)
+
+// LeaseOption is an option of the LeaseCache.
+type LeaseOption func(*LeaseCache)
+
+// WithLeaseTTL sets the time a lease stays valid, so the leases of the loads which never put are forgotten.
+// It should be longer than the loads. The default is 10s.
+func WithLeaseTTL(ttl time.Duration) LeaseOption {
+	return func(c *LeaseCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithLeaseClock sets the clock of the lease expiration, e.g. a FakeClock for deterministic tests.
+// SystemClock is used by default.
+func WithLeaseClock(clock Clock) LeaseOption {
+	return func(c *LeaseCache) {
+		c.clock = clock
+	}
+}
+
+var (
+	_ NamedCache    = &LeaseCache{}
+	_ Leaser        = &LeaseCache{}
+	_ WithTTLPutter = &LeaseCache{}
+	_ TTLGetter     = &LeaseCache{}
+	_ KeysGetter    = &LeaseCache{}
+	_ BatchPutter   = &LeaseCache{}
+	_ PrefixRemover = &LeaseCache{}
+)
+
+// LeaseCache is a cache with the memcache-style leases, which closes the race of a reader missing the key,
+// a writer updating the source and removing the key, and then the reader putting the old value:
+// a miss of GetWithLease gives a lease on the key, the writes and the removals of the key revoke it,
+// and PutWithLease puts the loaded value only with a valid lease.
+// The concurrent misses of a key share its lease, the first put consumes it.
+// Loader and the gRPC interceptor use the leases of the caches implementing Leaser.
+//
+// The wrapper does not lock the cache across the writes: a PutWithLease racing with a write of the key
+// removes its value after the put, so the cache never keeps the value loaded before the write.
+// Besides the methods of NamedCache it wraps PutWithTTL, PutMany, RemoveMany, RemoveByPrefix and RemoveMatching
+// (falling back to the methods of NamedCache if the cache lacks them), TTL and Keys.
+// The other writes (e.g. Update, CompareAndSwap) must not be called on the wrapped cache, they do not revoke the leases.
+type LeaseCache struct {
+	NamedCache
+
+	ttl   time.Duration
+	clock Clock
+
+	mu     sync.Mutex
+	leases map[interface{}]leaseEntry
+	next   uint64
+	// sweepAt is the number of the leases from which the expired ones are swept
+	sweepAt int
+	// writes are the writes in flight by key, PutWithLease checks them for the writes racing with its put
+	writes map[interface{}]*keyWrites
+	// bulk is the number of the Clear and RemoveMatching calls in flight, epoch is changed by each of them
+	bulk  int
+	epoch uint64
+}
+
+// keyWrites are the writes of a key in flight.
+type keyWrites struct {
+	// refs is the number of the writes in flight including the puts with the lease
+	refs int
+	// writers is the number of the writes in flight revoking the leases
+	writers int
+	// gen is changed by every write revoking the leases
+	gen uint64
+}
+
+// leaseEntry is the lease on a key, valid until it expires.
+type leaseEntry struct {
+	token   uint64
+	expires time.Time
+}
+
+// NewLeaseCache creates a LeaseCache over the cache c.
+func NewLeaseCache(c NamedCache, opts ...LeaseOption) (*LeaseCache, error) {
+	lc := &LeaseCache{
+		NamedCache: c,
+		ttl:        defaultLeaseTTL,
+		clock:      SystemClock,
+		leases:     make(map[interface{}]leaseEntry),
+		writes:     make(map[interface{}]*keyWrites),
+		sweepAt:    minLeaseSweep,
+	}
+	for _, opt := range opts {
+		opt(lc)
+	}
+
+	if lc.ttl <= 0 {
+		return nil, fmt.Errorf("can't create lease cache %s: %w", c.Name(), ErrWrongLeaseTTL)
+	}
+
+	return lc, nil
+}
+
+// GetWithLease returns the value for the key, or a lease token on a miss.
+// The lease of the key is shared until it is revoked, consumed or expired.
+func (c *LeaseCache) GetWithLease(key interface{}) (value interface{}, token uint64, ok bool) {
+	if v, ok := c.NamedCache.Get(key); ok {
+		return v, 0, true
+	}
+
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if l, ok := c.leases[key]; ok && now.Before(l.expires) {
+		return nil, l.token, false
+	}
+	if len(c.leases) >= c.sweepAt {
+		c.sweepLocked(now)
+	}
+	c.next++
+	c.leases[key] = leaseEntry{token: c.next, expires: now.Add(c.ttl)}
+	return nil, c.next, false
+}
+
+// PutWithLease puts the value if the lease of the key is valid and consumes the lease, otherwise it returns ErrLeaseInvalid.
+// If the key is written or removed during the put, the value is removed and ErrLeaseInvalid is returned.
+func (c *LeaseCache) PutWithLease(key, value interface{}, token uint64) error {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	l, ok := c.leases[key]
+	if !ok || l.token != token || !now.Before(l.expires) {
+		c.mu.Unlock()
+		return fmt.Errorf("can't put key %v: %w", key, ErrLeaseInvalid)
+	}
+	delete(c.leases, key)
+	w := c.trackLocked(key)
+	if w.writers > 0 || c.bulk > 0 {
+		// the value may be loaded before the write in flight
+		c.untrackLocked(key, w)
+		c.mu.Unlock()
+		return fmt.Errorf("can't put key %v: %w", key, ErrLeaseInvalid)
+	}
+	gen, epoch := w.gen, c.epoch
+	c.mu.Unlock()
+
+	c.NamedCache.Put(key, value)
+
+	c.mu.Lock()
+	raced := w.gen != gen || c.epoch != epoch
+	c.untrackLocked(key, w)
+	c.mu.Unlock()
+
+	if raced {
+		c.NamedCache.Remove(key)
+		return fmt.Errorf("can't put key %v: %w", key, ErrLeaseInvalid)
+	}
+	return nil
+}
+
+// Put stores the value and revokes the lease of the key, so a value loaded before is not put over it.
+func (c *LeaseCache) Put(key, value interface{}) {
+	c.beginWrites(key)
+	defer c.endWrites(key)
+
+	c.NamedCache.Put(key, value)
+}
+
+// PutWithTTL stores the value with the TTL like Put if the cache is a WithTTLPutter, otherwise it is Put.
+func (c *LeaseCache) PutWithTTL(key, value interface{}, ttl time.Duration) {
+	putter, ok := c.NamedCache.(WithTTLPutter)
+	if !ok {
+		c.Put(key, value)
+		return
+	}
+
+	c.beginWrites(key)
+	defer c.endWrites(key)
+
+	putter.PutWithTTL(key, value, ttl)
+}
+
+// PutMany stores the items like Put, in one batch if the cache is a BatchPutter.
+func (c *LeaseCache) PutMany(items []KeyValue) {
+	keys := make([]interface{}, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+	c.beginWrites(keys...)
+	defer c.endWrites(keys...)
+
+	if putter, ok := c.NamedCache.(BatchPutter); ok {
+		putter.PutMany(items)
+		return
+	}
+	for _, item := range items {
+		c.NamedCache.Put(item.Key, item.Value)
+	}
+}
+
+// Remove removes the key and revokes its lease, so a value loaded before the removal is not put.
+func (c *LeaseCache) Remove(key interface{}) {
+	c.beginWrites(key)
+	defer c.endWrites(key)
+
+	c.NamedCache.Remove(key)
+}
+
+// RemoveMany removes the keys like Remove, in one batch if the cache is a BatchPutter.
+func (c *LeaseCache) RemoveMany(keys []interface{}) {
+	c.beginWrites(keys...)
+	defer c.endWrites(keys...)
+
+	if remover, ok := c.NamedCache.(BatchPutter); ok {
+		remover.RemoveMany(keys)
+		return
+	}
+	for _, key := range keys {
+		c.NamedCache.Remove(key)
+	}
+}
+
+// RemoveByPrefix removes the entries which keys are strings with the prefix and revokes their leases,
+// it returns the number of removed entries (see RemoveMatching).
+func (c *LeaseCache) RemoveByPrefix(prefix string) int {
+	c.beginBulk(HasPrefix(prefix))
+	defer c.endBulk(HasPrefix(prefix))
+
+	if remover, ok := c.NamedCache.(PrefixRemover); ok {
+		return remover.RemoveByPrefix(prefix)
+	}
+	return c.removeScanned(HasPrefix(prefix))
+}
+
+// RemoveMatching removes the entries which keys match the predicate and revokes their leases,
+// it returns the number of removed entries. If the cache is not a PrefixRemover, its keys are scanned,
+// which needs a KeysGetter, otherwise nothing is removed.
+func (c *LeaseCache) RemoveMatching(match func(key interface{}) bool) int {
+	c.beginBulk(match)
+	defer c.endBulk(match)
+
+	if remover, ok := c.NamedCache.(PrefixRemover); ok {
+		return remover.RemoveMatching(match)
+	}
+	return c.removeScanned(match)
+}
+
+// removeScanned removes the keys of the cache matching the predicate one by one.
+func (c *LeaseCache) removeScanned(match func(key interface{}) bool) int {
+	kg, ok := c.NamedCache.(KeysGetter)
+	if !ok {
+		return 0
+	}
+	keys := FilterKeys(kg.Keys(), match)
+	for _, key := range keys {
+		c.NamedCache.Remove(key)
+	}
+	return len(keys)
+}
+
+// Clear clears the cache and revokes all the leases.
+func (c *LeaseCache) Clear() {
+	all := func(interface{}) bool { return true }
+	c.beginBulk(all)
+	defer c.endBulk(all)
+
+	c.NamedCache.Clear()
+}
+
+// TTL returns the default TTL of the cache, 0 if it is not a TTLGetter.
+func (c *LeaseCache) TTL() time.Duration {
+	if ttlGetter, ok := c.NamedCache.(TTLGetter); ok {
+		return ttlGetter.TTL()
+	}
+	return 0
+}
+
+// Keys returns the keys of the cache, nil if it is not a KeysGetter.
+func (c *LeaseCache) Keys() []interface{} {
+	if kg, ok := c.NamedCache.(KeysGetter); ok {
+		return kg.Keys()
+	}
+	return nil
+}
+
+// beginWrites revokes the leases of the keys and marks their writes in flight until endWrites.
+func (c *LeaseCache) beginWrites(keys ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.leases, key)
+		w := c.trackLocked(key)
+		w.writers++
+		w.gen++
+	}
+}
+
+// endWrites finishes the writes of the keys, the leases given during the writes are revoked too.
+func (c *LeaseCache) endWrites(keys ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.leases, key)
+		w := c.writes[key]
+		w.writers--
+		w.gen++
+		c.untrackLocked(key, w)
+	}
+}
+
+// beginBulk revokes the leases of the keys matching the predicate and marks the bulk removal in flight until endBulk.
+func (c *LeaseCache) beginBulk(match func(key interface{}) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.revokeMatchingLocked(match)
+	c.bulk++
+	c.epoch++
+}
+
+// endBulk finishes the bulk removal, the matching leases given during it are revoked too.
+func (c *LeaseCache) endBulk(match func(key interface{}) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.revokeMatchingLocked(match)
+	c.bulk--
+	c.epoch++
+}
+
+func (c *LeaseCache) revokeMatchingLocked(match func(key interface{}) bool) {
+	for key := range c.leases {
+		if match(key) {
+			delete(c.leases, key)
+		}
+	}
+}
+
+// trackLocked returns the writes of the key adding one more, c.mu must be held.
+func (c *LeaseCache) trackLocked(key interface{}) *keyWrites {
+	w, ok := c.writes[key]
+	if !ok {
+		w = &keyWrites{}
+		c.writes[key] = w
+	}
+	w.refs++
+	return w
+}
+
+// untrackLocked removes one write of the key, c.mu must be held.
+func (c *LeaseCache) untrackLocked(key interface{}, w *keyWrites) {
+	w.refs--
+	if w.refs == 0 {
+		delete(c.writes, key)
+	}
+}
+
+// sweepLocked forgets the expired leases, the next sweep happens when their number doubles.
+func (c *LeaseCache) sweepLocked(now time.Time) {
+	for key, l := range c.leases {
+		if !now.Before(l.expires) {
+			delete(c.leases, key)
+		}
+	}
+	c.sweepAt = max(2*len(c.leases), minLeaseSweep)
+}
@@ -0,0 +1,152 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaseCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("put with valid lease", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewLeaseCache(base)
+		require.NoError(t, err)
+
+		// act
+		_, lease, ok := c.GetWithLease("key")
+		_, shared, _ := c.GetWithLease("key")
+		errPut := c.PutWithLease("key", "value", lease)
+		errConsumed := c.PutWithLease("key", "other", lease)
+		v, hitLease, hit := c.GetWithLease("key")
+
+		// assert
+		assert.False(t, ok)
+		assert.NotZero(t, lease)
+		assert.Equal(t, lease, shared)
+		assert.NoError(t, errPut)
+		assert.ErrorIs(t, errConsumed, cache.ErrLeaseInvalid)
+		assert.True(t, hit)
+		assert.Zero(t, hitLease)
+		assert.Equal(t, "value", v)
+	})
+
+	t.Run("remove and put revoke lease", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewLeaseCache(base)
+		require.NoError(t, err)
+		_, removedLease, _ := c.GetWithLease("removed")
+		_, writtenLease, _ := c.GetWithLease("written")
+
+		// act
+		c.Remove("removed")
+		c.Put("written", "new")
+		errRemoved := c.PutWithLease("removed", "old", removedLease)
+		errWritten := c.PutWithLease("written", "old", writtenLease)
+		_, newLease, _ := c.GetWithLease("removed")
+
+		// assert
+		assert.ErrorIs(t, errRemoved, cache.ErrLeaseInvalid)
+		assert.ErrorIs(t, errWritten, cache.ErrLeaseInvalid)
+		assert.False(t, c.Contains("removed"))
+		v, _ := c.Get("written")
+		assert.Equal(t, "new", v)
+		assert.NotEqual(t, removedLease, newLease)
+	})
+
+	t.Run("put racing with lease put", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		blocking := &blockingPutCache{NamedCache: base, started: make(chan struct{}), release: make(chan struct{})}
+		c, err := cache.NewLeaseCache(blocking)
+		require.NoError(t, err)
+		_, lease, _ := c.GetWithLease("key")
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- c.PutWithLease("key", "old", lease)
+		}()
+		<-blocking.started
+
+		// act
+		c.Put("key", "new")
+		close(blocking.release)
+		errLease := <-errCh
+
+		// assert
+		assert.ErrorIs(t, errLease, cache.ErrLeaseInvalid)
+		// the old value overwrote the new one, so it is removed
+		assert.False(t, c.Contains("key"))
+	})
+
+	t.Run("wrapped writes revoke leases", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewLeaseCache(base)
+		require.NoError(t, err)
+		keys := []string{"ttl", "many", "removed", "user:1"}
+		leases := make(map[string]uint64, len(keys))
+		for _, key := range keys {
+			_, leases[key], _ = c.GetWithLease(key)
+		}
+
+		// act
+		c.PutWithTTL("ttl", "new", time.Minute)
+		c.PutMany([]cache.KeyValue{{Key: "many", Value: "new"}})
+		c.RemoveMany([]interface{}{"removed"})
+		removed := c.RemoveByPrefix("user:")
+
+		// assert
+		assert.Zero(t, removed)
+		for _, key := range keys {
+			assert.ErrorIs(t, c.PutWithLease(key, "old", leases[key]), cache.ErrLeaseInvalid, key)
+		}
+		assert.ElementsMatch(t, []interface{}{"ttl", "many"}, c.Keys())
+	})
+
+	t.Run("lease expires", func(t *testing.T) {
+		t.Parallel()
+
+		clock := cache.NewFakeClock(time.Now())
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewLeaseCache(base, cache.WithLeaseTTL(time.Second), cache.WithLeaseClock(clock))
+		require.NoError(t, err)
+		_, lease, _ := c.GetWithLease("key")
+
+		// act
+		clock.Advance(time.Second)
+		errExpired := c.PutWithLease("key", "value", lease)
+
+		// assert
+		assert.ErrorIs(t, errExpired, cache.ErrLeaseInvalid)
+		assert.False(t, c.Contains("key"))
+	})
+
+	t.Run("wrong lease TTL", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		// act
+		_, err = cache.NewLeaseCache(base, cache.WithLeaseTTL(0))
+
+		// assert
+		assert.ErrorIs(t, err, cache.ErrWrongLeaseTTL)
+	})
+}
@@ -0,0 +1,755 @@
+package lfu
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/metrics"
+)
+
+var (
+	_ cache.NamedCache        = &Cache{}
+	_ cache.WithTTLPutter     = &Cache{}
+	_ cache.KeysGetter        = &Cache{}
+	_ cache.CapSetter         = &Cache{}
+	_ cache.SoftTTLPutter     = &Cache{}
+	_ cache.StaleGetter       = &Cache{}
+	_ cache.TTLGetter         = &Cache{}
+	_ cache.ComputeTimePutter = &Cache{}
+	_ cache.ComputeTimeGetter = &Cache{}
+	_ cache.BatchGetter       = &Cache{}
+	_ cache.BatchPutter       = &Cache{}
+	_ cache.PrefixRemover     = &Cache{}
+	_ cache.AtomicUpdater     = &Cache{}
+	_ cache.Versioner         = &Cache{}
+	_ io.Closer               = &Cache{}
+)
+
+// Cache is a native LFU (least frequently used) cache.
+// Get and Put are O(1): items are kept in buckets by access count,
+// the buckets form a list ordered by the count, so the eviction victim is
+// the oldest item of the first bucket.
+type Cache struct {
+	mu    sync.Mutex
+	items map[interface{}]*item
+	// buckets is the sentinel of the circular bucket list: buckets.next has the lowest access count.
+	buckets bucket
+
+	onEvict       func(key, value interface{})
+	decayInterval time.Duration
+	decayFactor   float64
+
+	close   chan struct{}
+	metrics *metrics.CacheMetrics
+	name    string
+	cap     int
+	ttl     time.Duration
+	jitter  *cache.Jitter
+	// keys is the index of the string keys, nil if it is disabled (see WithKeyIndex)
+	keys  *cache.KeyIndex
+	clock cache.Clock
+	// version is the version of the last write (see PutIfVersion)
+	version atomic.Uint64
+}
+
+const (
+	calcItemNumberInterval = 15 * time.Second
+)
+
+// ErrWrongDecayFactor is the decay factor error if it is not in (0, 1).
+var ErrWrongDecayFactor = errors.New("wrong decay factor, it should be in (0, 1)")
+
+// NewCache creates a new LFU cache with capacity and ttl.
+// If ttl == 0, the cache will be without ttl.
+func NewCache(name string, cap int, ttl time.Duration, opts ...Option) (*Cache, error) {
+	oo := &options{
+		clock: cache.SystemClock,
+	}
+	for _, o := range opts {
+		o(oo)
+	}
+	if cap <= 0 {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, cache.ErrWrongCapacity)
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, cache.ErrWrongTTL)
+	}
+	if oo.decayInterval > 0 && (oo.decayFactor <= 0 || oo.decayFactor >= 1) {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, ErrWrongDecayFactor)
+	}
+	jitter, err := cache.NewJitter(oo.jitter, oo.jitterPercent, oo.randSource)
+	if err != nil {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, err)
+	}
+
+	c := &Cache{
+		items:         make(map[interface{}]*item),
+		onEvict:       oo.onEvict,
+		decayInterval: oo.decayInterval,
+		decayFactor:   oo.decayFactor,
+		name:          name,
+		cap:           cap,
+		ttl:           ttl,
+		close:         make(chan struct{}),
+		metrics:       metrics.NewCacheMetrics(name),
+		jitter:        jitter,
+		clock:         oo.clock,
+	}
+	if oo.keyIndex {
+		c.keys = cache.NewKeyIndex()
+		c.onEvict = c.keys.OnEvict(c.onEvict)
+	}
+	c.buckets.next = &c.buckets
+	c.buckets.prev = &c.buckets
+
+	go c.stats()
+	if c.decayInterval > 0 {
+		go c.decayLoop()
+	}
+
+	return c, nil
+}
+
+// Cap returns the cache capacity.
+func (c *Cache) Cap() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cap
+}
+
+// TTL returns the default TTL of the cache, 0 means no TTL.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Len returns the number of items in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
+
+// Clear completely clears the cache.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.onEvict != nil {
+		for _, i := range c.items {
+			c.onEvict(i.key, i.value)
+		}
+	}
+	c.items = make(map[interface{}]*item)
+	c.buckets.next = &c.buckets
+	c.buckets.prev = &c.buckets
+}
+
+// Close completely clears the cache.
+// Always call Close after finishing using the cache to release resources.
+func (c *Cache) Close() error {
+	select {
+	case _, ok := <-c.close:
+		if !ok {
+			return nil
+		}
+	default:
+	}
+
+	c.Clear()
+	close(c.close)
+
+	return nil
+}
+
+// Contains checks for the presence of a not expired key in the cache without updating its access count.
+func (c *Cache) Contains(key interface{}) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Put puts a key-value pair into the cache.
+// It uses the default TTL specified in NewCache.
+func (c *Cache) Put(key, value interface{}) {
+	c.PutWithTTL(key, value, c.ttl)
+}
+
+// PutWithTTL adds a key-value pair to the cache with a specified TTL.
+// If ttl <= 0, no TTL is added.
+// Updating an existing key counts as an access.
+func (c *Cache) PutWithTTL(key, value interface{}, ttl time.Duration) {
+	c.put(key, value, 0, ttl, 0)
+}
+
+// PutWithComputeTime adds a key-value pair to the cache with a specified TTL and the time it took to compute the value,
+// which is used by the probabilistic early expiration (see cache.WithXFetch).
+func (c *Cache) PutWithComputeTime(key, value interface{}, ttl, computeTime time.Duration) {
+	c.put(key, value, 0, ttl, computeTime)
+}
+
+// PutWithSoftTTL adds a key-value pair to the cache with the soft and hard TTLs.
+// After softTTL the entry is stale: Get and Peek treat it as expired, GetStale returns it until hardTTL.
+// If softTTL <= 0, the entry never becomes stale. If hardTTL <= 0, no hard TTL is added.
+func (c *Cache) PutWithSoftTTL(key, value interface{}, softTTL, hardTTL time.Duration) {
+	c.put(key, value, softTTL, hardTTL, 0)
+}
+
+func (c *Cache) put(key, value interface{}, softTTL, ttl, computeTime time.Duration) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	expires, stale := c.expiration(start, softTTL, ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addLocked(key, value, expires, stale, computeTime)
+}
+
+// expiration returns the hard and the soft expiration time of an entry put at now, the jitter of the cache is added to both.
+func (c *Cache) expiration(now time.Time, softTTL, ttl time.Duration) (expires, stale time.Time) {
+	// the same jitter is added to the soft TTL to keep the stale period
+	jitter := c.jitter.Duration(ttl)
+	ttl += jitter
+	if softTTL > 0 {
+		softTTL += jitter
+	}
+
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+	if softTTL > 0 && (ttl <= 0 || softTTL < ttl) {
+		stale = now.Add(softTTL)
+	}
+	return expires, stale
+}
+
+// addLocked adds the entry to the cache or updates it, c.mu must be held.
+func (c *Cache) addLocked(key, value interface{}, expires, stale time.Time, computeTime time.Duration) {
+	if i, ok := c.items[key]; ok {
+		i.value = value
+		i.expires = expires
+		i.stale = stale
+		i.computeTime = computeTime
+		i.version = c.version.Add(1)
+		c.increment(i)
+		return
+	}
+
+	for len(c.items) >= c.cap {
+		c.evict()
+	}
+
+	i := &item{key: key, value: value, expires: expires, stale: stale, computeTime: computeTime, version: c.version.Add(1)}
+	first := c.buckets.next
+	if first == &c.buckets || first.freq != 1 {
+		first = c.insertBucketAfter(&c.buckets, 1)
+	}
+	first.pushFront(i)
+	c.items[key] = i
+	c.keys.Insert(key)
+}
+
+// Get retrieves a value by a specific key from the cache and increments its access count,
+// and returns a boolean indicating whether the value was found.
+// If the TTL of the key has expired, the key is removed and it returns nil and false.
+// A stale key (see PutWithSoftTTL) is reported as expired, but kept until its hard TTL.
+func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	r := c.get(key, start, false)
+	expired = r.expired
+	return r.value, r.ok
+}
+
+// GetStale retrieves a value by key like Get, but also returns the entries which soft TTL has passed
+// (see PutWithSoftTTL), stale reports whether the soft TTL of the entry has passed.
+func (c *Cache) GetStale(key interface{}) (value interface{}, stale bool, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok && stale {
+			c.metrics.StaleCount.Inc()
+		} else if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	r := c.get(key, start, true)
+	expired = r.expired
+	return r.value, r.stale, r.ok
+}
+
+// GetWithComputeTime retrieves a value by key like Get, with its expiration time and the time it took to compute it
+// (see PutWithComputeTime).
+func (c *Cache) GetWithComputeTime(key interface{}) (value interface{}, expires time.Time, computeTime time.Duration, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	r := c.get(key, start, false)
+	expired = r.expired
+	return r.value, r.expires, r.computeTime, r.ok
+}
+
+// lookup is the result of get.
+type lookup struct {
+	value       interface{}
+	expires     time.Time
+	computeTime time.Duration
+	version     uint64
+	ok          bool
+	stale       bool
+	expired     bool
+}
+
+// get returns the value of the key and increments its access count.
+// The stale items are returned only if withStale is set, otherwise they are reported as expired but kept until the hard TTL.
+func (c *Cache) get(key interface{}, now time.Time, withStale bool) lookup {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.getLocked(key, now, withStale)
+}
+
+// getLocked is get, c.mu must be held.
+func (c *Cache) getLocked(key interface{}, now time.Time, withStale bool) lookup {
+	i, ok := c.items[key]
+	if !ok {
+		return lookup{}
+	}
+	if i.expired(now) {
+		c.removeItem(i)
+		return lookup{expired: true}
+	}
+	stale := i.isStale(now)
+	if stale && !withStale {
+		return lookup{expired: true}
+	}
+	c.increment(i)
+	return lookup{value: i.value, expires: i.expires, computeTime: i.computeTime, version: i.version, ok: true, stale: stale}
+}
+
+// Peek retrieves a value by key without updating its access count,
+// and returns a boolean indicating whether the value was found.
+// If the TTL of the key has expired, it returns nil and false.
+func (c *Cache) Peek(key interface{}) (value interface{}, ok bool) {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i, ok := c.items[key]
+	if !ok || i.expired(now) || i.isStale(now) {
+		return nil, false
+	}
+	return i.value, true
+}
+
+// Remove removes a value by key from the cache.
+func (c *Cache) Remove(key interface{}) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if i, ok := c.items[key]; ok {
+		c.removeItem(i)
+	}
+}
+
+// GetMany retrieves the values by keys like Get, it returns the found values by key
+// and the keys which are missing or expired, in the order of keys. The cache is locked and the metrics are recorded once per batch.
+func (c *Cache) GetMany(keys []interface{}) (hits map[interface{}]interface{}, missing []interface{}) {
+	start := c.clock.Now()
+	hit, expired := 0, 0
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		c.metrics.HitCount.Add(float64(hit))
+		c.metrics.ExpiredCount.Add(float64(expired))
+		c.metrics.MissCount.Add(float64(len(missing) - expired))
+	}()
+
+	hits = make(map[interface{}]interface{}, len(keys))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		r := c.getLocked(key, start, false)
+		if r.ok {
+			hits[key] = r.value
+			hit++
+			continue
+		}
+		if r.expired {
+			expired++
+		}
+		missing = append(missing, key)
+	}
+	return hits, missing
+}
+
+// PutMany adds the key-value pairs to the cache with the default TTL, in the order of items.
+// The cache is locked and the metrics are recorded once per batch.
+func (c *Cache) PutMany(items []cache.KeyValue) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	expires := make([]time.Time, len(items))
+	for i := range items {
+		expires[i], _ = c.expiration(start, 0, c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, item := range items {
+		c.addLocked(item.Key, item.Value, expires[i], time.Time{}, 0)
+	}
+}
+
+// RemoveMany removes the values by keys from the cache, the cache is locked and the metrics are recorded once per batch.
+func (c *Cache) RemoveMany(keys []interface{}) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if i, ok := c.items[key]; ok {
+			c.removeItem(i)
+		}
+	}
+}
+
+// RemoveByPrefix removes the entries which keys are strings with the prefix, it returns the number of removed entries.
+// With WithKeyIndex the keys are found in the index, otherwise all keys are scanned.
+func (c *Cache) RemoveByPrefix(prefix string) int {
+	var keys []interface{}
+	if c.keys != nil {
+		keys = c.keys.KeysWithPrefix(prefix)
+	} else {
+		keys = cache.FilterKeys(c.Keys(), cache.HasPrefix(prefix))
+	}
+	c.RemoveMany(keys)
+	return len(keys)
+}
+
+// RemoveMatching removes the entries which keys match the predicate, it returns the number of removed entries.
+// All keys are scanned.
+func (c *Cache) RemoveMatching(match func(key interface{}) bool) int {
+	keys := cache.FilterKeys(c.Keys(), match)
+	c.RemoveMany(keys)
+	return len(keys)
+}
+
+// PutIfAbsent puts the value with the default TTL if the key is absent, expired or stale,
+// it returns the current value of the key and whether it was loaded.
+func (c *Cache) PutIfAbsent(key, value interface{}) (actual interface{}, loaded bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	expires, stale := c.expiration(start, 0, c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.currentLocked(key, start); ok {
+		return old, true
+	}
+	c.addLocked(key, value, expires, stale, 0)
+	return value, false
+}
+
+// CompareAndSwap replaces the value of the key with newValue and the default TTL if the current value equals oldValue,
+// it reports whether the value was swapped. The absent, expired and stale keys are not swapped, the values must be comparable.
+func (c *Cache) CompareAndSwap(key, oldValue, newValue interface{}) bool {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	expires, stale := c.expiration(start, 0, c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.currentLocked(key, start); !ok || old != oldValue {
+		return false
+	}
+	c.addLocked(key, newValue, expires, stale, 0)
+	return true
+}
+
+// Update calls fn with the current value of the key (exists is false if it is absent, expired or stale)
+// and puts the returned value with the default TTL if keep is true, otherwise it removes the key.
+// It returns the new value and keep. fn is called under the cache lock, so it must not call the cache.
+func (c *Cache) Update(
+	key interface{},
+	fn func(old interface{}, exists bool) (value interface{}, keep bool),
+) (value interface{}, ok bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	expires, stale := c.expiration(start, 0, c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, exists := c.currentLocked(key, start)
+	value, ok = fn(old, exists)
+	if !ok {
+		if i, found := c.items[key]; found {
+			c.removeItem(i)
+		}
+		return nil, false
+	}
+	c.addLocked(key, value, expires, stale, 0)
+	return value, true
+}
+
+// GetVersioned retrieves a value by key like Get, with the version of the entry (see PutIfVersion).
+func (c *Cache) GetVersioned(key interface{}) (value interface{}, version uint64, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	r := c.get(key, start, false)
+	expired = r.expired
+	return r.value, r.version, r.ok
+}
+
+// PutIfVersion puts the value with the default TTL if the version of the key is expectedVersion,
+// 0 means the key is absent, expired or stale. Otherwise it returns *cache.VersionMismatchError.
+func (c *Cache) PutIfVersion(key, value interface{}, expectedVersion uint64) error {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	expires, stale := c.expiration(start, 0, c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var version uint64
+	if i, ok := c.items[key]; ok && !i.expired(start) && !i.isStale(start) {
+		version = i.version
+	}
+	if version != expectedVersion {
+		return &cache.VersionMismatchError{Key: key, Expected: expectedVersion, Actual: version}
+	}
+	c.addLocked(key, value, expires, stale, 0)
+	return nil
+}
+
+// currentLocked returns the fresh value of the key without counting the access, c.mu must be held.
+func (c *Cache) currentLocked(key interface{}, now time.Time) (interface{}, bool) {
+	i, ok := c.items[key]
+	if !ok || i.expired(now) || i.isStale(now) {
+		return nil, false
+	}
+	return i.value, true
+}
+
+// Name returns the cache name.
+func (c *Cache) Name() string {
+	return c.name
+}
+
+// Keys returns a list of saved keys, from the first to be evicted to the last one.
+func (c *Cache) Keys() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]interface{}, 0, len(c.items))
+	for b := c.buckets.next; b != &c.buckets; b = b.next {
+		for i := b.root.prev; i != &b.root; i = i.prev {
+			keys = append(keys, i.key)
+		}
+	}
+	return keys
+}
+
+// SetCap sets the capacity of the cache to cap, evicting the least frequently used items above it.
+func (c *Cache) SetCap(cap int) error {
+	if cap <= 0 {
+		return cache.ErrWrongCapacity
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cap = cap
+	for len(c.items) > c.cap {
+		c.evict()
+	}
+
+	return nil
+}
+
+// increment moves the item to the bucket of the next access count.
+func (c *Cache) increment(i *item) {
+	cur := i.bucket
+	next := cur.next
+	if next == &c.buckets || next.freq != cur.freq+1 {
+		next = c.insertBucketAfter(cur, cur.freq+1)
+	}
+	unlinkItem(i)
+	next.pushFront(i)
+	if cur.empty() {
+		c.removeBucket(cur)
+	}
+}
+
+// evict removes the oldest item with the lowest access count.
+func (c *Cache) evict() {
+	if b := c.buckets.next; b != &c.buckets {
+		c.removeItem(b.oldest())
+	}
+}
+
+func (c *Cache) removeItem(i *item) {
+	b := i.bucket
+	unlinkItem(i)
+	i.bucket = nil
+	if b.empty() {
+		c.removeBucket(b)
+	}
+	delete(c.items, i.key)
+	if c.onEvict != nil {
+		c.onEvict(i.key, i.value)
+	}
+}
+
+func (c *Cache) insertBucketAfter(prev *bucket, freq uint64) *bucket {
+	b := newBucket(freq)
+	b.prev = prev
+	b.next = prev.next
+	b.prev.next = b
+	b.next.prev = b
+	return b
+}
+
+func (c *Cache) removeBucket(b *bucket) {
+	b.prev.next = b.next
+	b.next.prev = b.prev
+	b.prev = nil
+	b.next = nil
+}
+
+// decay multiplies the access counts by the decay factor.
+// The order of the buckets is kept, the buckets that end up with the same count are merged,
+// so the items with the lower count before the decay are still evicted first.
+func (c *Cache) decay() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var prev *bucket
+	for b := c.buckets.next; b != &c.buckets; {
+		next := b.next
+
+		freq := uint64(float64(b.freq) * c.decayFactor)
+		if freq < 1 {
+			freq = 1
+		}
+		b.freq = freq
+		if prev != nil && prev.freq == freq {
+			b.appendBucket(prev)
+			c.removeBucket(prev)
+		}
+		prev = b
+
+		b = next
+	}
+}
+
+func (c *Cache) decayLoop() {
+	for {
+		select {
+		case <-c.clock.After(c.decayInterval):
+		case <-c.close:
+			return
+		}
+		c.decay()
+	}
+}
+
+func (c *Cache) stats() {
+	for {
+		select {
+		case <-c.clock.After(calcItemNumberInterval):
+		case <-c.close:
+			return
+		}
+		c.metrics.ItemNumber.Set(float64(c.Len()))
+	}
+}
@@ -0,0 +1,86 @@
+package lfu
+
+import "time"
+
+// item is a cache entry, it belongs to the bucket of its access count.
+type item struct {
+	key     interface{}
+	value   interface{}
+	expires time.Time
+	stale   time.Time
+	// computeTime is the time it took to compute the value (see Cache.PutWithComputeTime)
+	computeTime time.Duration
+	// version is the version of the write (see Cache.PutIfVersion)
+	version uint64
+
+	bucket     *bucket
+	prev, next *item
+}
+
+func (i *item) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// isStale reports whether the soft TTL of the item has passed.
+func (i *item) isStale(now time.Time) bool {
+	return !i.stale.IsZero() && !now.Before(i.stale)
+}
+
+// bucket holds all the items with the same access count.
+// Inside the bucket items are ordered by recency, root.prev is the oldest one and the first to be evicted.
+type bucket struct {
+	freq uint64
+	root item
+
+	prev, next *bucket
+}
+
+func newBucket(freq uint64) *bucket {
+	b := &bucket{freq: freq}
+	b.root.next = &b.root
+	b.root.prev = &b.root
+	return b
+}
+
+func (b *bucket) empty() bool {
+	return b.root.next == &b.root
+}
+
+func (b *bucket) pushFront(i *item) {
+	i.bucket = b
+	i.prev = &b.root
+	i.next = b.root.next
+	i.prev.next = i
+	i.next.prev = i
+}
+
+func (b *bucket) oldest() *item {
+	return b.root.prev
+}
+
+func unlinkItem(i *item) {
+	i.prev.next = i.next
+	i.next.prev = i.prev
+	i.prev = nil
+	i.next = nil
+}
+
+// appendBucket moves all the items of src to the back of b, keeping their order.
+func (b *bucket) appendBucket(src *bucket) {
+	if src.empty() {
+		return
+	}
+	for i := src.root.next; i != &src.root; i = i.next {
+		i.bucket = b
+	}
+	first, last := src.root.next, src.root.prev
+	tail := b.root.prev
+
+	tail.next = first
+	first.prev = tail
+	last.next = &b.root
+	b.root.prev = last
+
+	src.root.next = &src.root
+	src.root.prev = &src.root
+}
@@ -0,0 +1,81 @@
+package lfu
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+)
+
+type options struct {
+	decayInterval time.Duration
+	decayFactor   float64
+	onEvict       func(key, value interface{})
+	jitter        time.Duration
+	jitterPercent float64
+	randSource    rand.Source
+	clock         cache.Clock
+	keyIndex      bool
+}
+
+// Option configures the LFU cache.
+type Option func(*options)
+
+// WithDecay enables aging of the access counts: every interval the count of each item is multiplied by factor
+// (0 < factor < 1, counts never go below 1), so items that were popular in the past
+// do not stay in the cache forever once the popularity shifts.
+// By default there is no decay.
+func WithDecay(interval time.Duration, factor float64) Option {
+	return func(o *options) {
+		o.decayInterval = interval
+		o.decayFactor = factor
+	}
+}
+
+// WithEvictCallback sets a function to be called when an item leaves the cache
+// (evicted by capacity, expired, removed or purged).
+// The callback is called under the cache lock, so it must not call the cache.
+func WithEvictCallback(onEvict func(key, value interface{})) Option {
+	return func(o *options) {
+		o.onEvict = onEvict
+	}
+}
+
+// WithTTLJitter adds a random jitter from 0 to max to the TTL of every entry,
+// so the entries put at the same time do not expire at the same moment.
+func WithTTLJitter(max time.Duration) Option {
+	return func(o *options) {
+		o.jitter = max
+	}
+}
+
+// WithTTLJitterPercent adds a random jitter from 0 to percent of the TTL (e.g. 0.1 for 10%) to the TTL of every entry.
+// It can be combined with WithTTLJitter.
+func WithTTLJitterPercent(percent float64) Option {
+	return func(o *options) {
+		o.jitterPercent = percent
+	}
+}
+
+// WithRandSource sets the random source of the TTL jitter, e.g. a fixed-seed source for deterministic tests.
+func WithRandSource(src rand.Source) Option {
+	return func(o *options) {
+		o.randSource = src
+	}
+}
+
+// WithClock sets the clock of the cache used for the expiration, the metrics timing and the background loops,
+// e.g. a cache.FakeClock for deterministic tests. cache.SystemClock is used by default.
+func WithClock(clock cache.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithKeyIndex keeps the string keys in a radix tree, so RemoveByPrefix finds the keys without scanning all of them.
+// The index costs the memory for the keys and an update on every insertion and removal.
+func WithKeyIndex() Option {
+	return func(o *options) {
+		o.keyIndex = true
+	}
+}
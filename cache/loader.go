@@ -0,0 +1,424 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache/metrics"
+	"github.com/catalystgo/tracerok/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrStaleNotSupported is the error if the cache can't keep stale entries (see SoftTTLPutter and StaleGetter).
+	ErrStaleNotSupported = errors.New("cache doesn't support stale entries")
+	// ErrWrongRefreshRatio is the refresh-ahead ratio error if it is not in (0, 1).
+	ErrWrongRefreshRatio = errors.New("wrong refresh ratio, it should be in (0, 1)")
+	// ErrWrongConcurrency is the refresh concurrency error if it is less than 1.
+	ErrWrongConcurrency = errors.New("wrong concurrency, it should be positive")
+	// ErrComputeTimeNotSupported is the error if the cache can't keep the compute time of entries
+	// (see ComputeTimePutter and ComputeTimeGetter).
+	ErrComputeTimeNotSupported = errors.New("cache doesn't support compute time of entries")
+	// ErrWrongBeta is the XFetch beta error if it is not positive.
+	ErrWrongBeta = errors.New("wrong beta, it should be positive")
+	// ErrIncompatibleOptions is the error if the options of the loader can't be used together.
+	ErrIncompatibleOptions = errors.New("incompatible options")
+)
+
+// LoadFunc loads the value for the key from the source of truth (database, service, etc.).
+type LoadFunc func(ctx context.Context, key interface{}) (interface{}, error)
+
+// LoaderOption is an option of the Loader.
+type LoaderOption func(*Loader)
+
+// WithStaleWhileRevalidate enables the stale-while-revalidate mode:
+// the loaded values are fresh for ttl, and then served stale for the grace period,
+// while a single background load refreshes the value. After ttl + grace the value is loaded synchronously.
+// The cache must implement SoftTTLPutter and StaleGetter.
+func WithStaleWhileRevalidate(ttl, grace time.Duration) LoaderOption {
+	return func(l *Loader) {
+		l.softTTL = ttl
+		l.grace = grace
+		l.staleMode = true
+	}
+}
+
+// WithRefreshAhead enables the refresh-ahead mode: the loaded values live for ttl,
+// and a value accessed within the last ratio of its TTL (e.g. 0.2 for the last 20%) is returned
+// and reloaded in the background, so the hot keys do not expire.
+// It is the stale-while-revalidate mode with the soft TTL ttl*(1-ratio) and the grace period ttl*ratio,
+// so it replaces WithStaleWhileRevalidate. The cache must implement SoftTTLPutter and StaleGetter.
+func WithRefreshAhead(ttl time.Duration, ratio float64) LoaderOption {
+	return func(l *Loader) {
+		l.staleMode = true
+		l.refreshAhead = true
+		l.refreshRatio = ratio
+		l.grace = time.Duration(float64(ttl) * ratio)
+		l.softTTL = ttl - l.grace
+	}
+}
+
+// WithRefreshConcurrency limits the number of the background refreshes running at the same time.
+// The refreshes over the limit are skipped, the value is refreshed on one of the next accesses.
+// By default the number of the refreshes is not limited.
+func WithRefreshConcurrency(n int) LoaderOption {
+	return func(l *Loader) {
+		l.refreshLimit = n
+	}
+}
+
+// WithStaleIfError keeps the expired values for the extra window and returns them when LoadFunc fails,
+// optionally only for the errors with the given gRPC codes (e.g. codes.Unavailable).
+// The values are fresh for the default TTL of the cache (see TTLGetter), or for the ttl of WithStaleWhileRevalidate,
+// in which case the stale values are served anyway and the window extends the grace period.
+// The cache must implement SoftTTLPutter and StaleGetter.
+func WithStaleIfError(window time.Duration, errCodes ...codes.Code) LoaderOption {
+	return func(l *Loader) {
+		l.staleIfError = &staleIfError{window: window, codes: errCodes}
+	}
+}
+
+// WithXFetch enables the probabilistic early expiration (XFetch) to prevent stampedes without locks:
+// every read may treat the value as expired before its TTL, with the probability growing as the expiration approaches
+// and with the time it took to load the value. beta scales the eagerness, 1 is the optimal default, > 1 favors earlier loads.
+// The values are put with the default TTL of the cache (see TTLGetter), the values without a TTL are never loaded early.
+// The cache must implement ComputeTimePutter and ComputeTimeGetter.
+// It can't be combined with WithStaleWhileRevalidate, WithRefreshAhead and WithStaleIfError.
+func WithXFetch(beta float64) LoaderOption {
+	return func(l *Loader) {
+		l.xfetch = true
+		l.beta = beta
+	}
+}
+
+// WithLoaderErrorf sets the logger of the background refresh errors.
+func WithLoaderErrorf(f func(ctx context.Context, format string, args ...interface{})) LoaderOption {
+	return func(l *Loader) {
+		l.logErrorf = f
+	}
+}
+
+// WithLoaderClock sets the clock of the XFetch expiration checks and of the measured load times,
+// e.g. a FakeClock for deterministic tests. It should match the clock of the cache. SystemClock is used by default.
+func WithLoaderClock(clock Clock) LoaderOption {
+	return func(l *Loader) {
+		l.clock = clock
+	}
+}
+
+// Loader is a read-through wrapper over a cache: it loads the missing values with LoadFunc and puts them into the cache.
+// Concurrent loads of the same key are deduplicated, so the source is called once per key at a time.
+type Loader struct {
+	cache   Cache
+	load    LoadFunc
+	metrics *metrics.CacheMetrics
+	clock   Clock
+
+	staleMode    bool
+	softTTL      time.Duration
+	grace        time.Duration
+	staleIfError *staleIfError
+	putter       SoftTTLPutter
+	getter       StaleGetter
+
+	xfetch        bool
+	beta          float64
+	ttl           time.Duration
+	computePutter ComputeTimePutter
+	computeGetter ComputeTimeGetter
+
+	leaser Leaser
+
+	refreshAhead bool
+	refreshRatio float64
+	refreshLimit int
+	// refreshSem bounds the background refreshes, nil means no limit
+	refreshSem chan struct{}
+
+	mu    sync.Mutex
+	calls map[interface{}]*loadCall
+
+	logErrorf func(ctx context.Context, format string, args ...interface{})
+}
+
+// loadCall is an in-flight load of a key, done is closed when the load is finished.
+type loadCall struct {
+	done  chan struct{}
+	lease uint64
+	value interface{}
+	err   error
+}
+
+// NewLoader creates a Loader over the cache c, which loads the missing values with load.
+func NewLoader(c Cache, load LoadFunc, opts ...LoaderOption) (*Loader, error) {
+	var name string
+	if named, ok := c.(Named); ok {
+		name = named.Name()
+	}
+
+	l := &Loader{
+		cache:     c,
+		load:      load,
+		metrics:   metrics.NewCacheMetrics(name),
+		clock:     SystemClock,
+		calls:     make(map[interface{}]*loadCall),
+		logErrorf: logger.Errorf,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.refreshAhead && (l.refreshRatio <= 0 || l.refreshRatio >= 1) {
+		return nil, fmt.Errorf("can't create loader %s: %w", name, ErrWrongRefreshRatio)
+	}
+	if l.refreshLimit < 0 {
+		return nil, fmt.Errorf("can't create loader %s: %w", name, ErrWrongConcurrency)
+	}
+	if l.refreshLimit > 0 {
+		l.refreshSem = make(chan struct{}, l.refreshLimit)
+	}
+
+	if leaser, ok := c.(Leaser); ok {
+		l.leaser = leaser
+	}
+
+	if l.xfetch {
+		return l.initXFetch(name)
+	}
+	if !l.staleMode && l.staleIfError == nil {
+		return l, nil
+	}
+	if l.staleMode && (l.softTTL <= 0 || l.grace < 0) {
+		return nil, fmt.Errorf("can't create loader %s: %w", name, ErrWrongTTL)
+	}
+	if l.staleIfError != nil && l.staleIfError.window < 0 {
+		return nil, fmt.Errorf("can't create loader %s: %w", name, ErrWrongTTL)
+	}
+	putter, okPut := c.(SoftTTLPutter)
+	getter, okGet := c.(StaleGetter)
+	if !okPut || !okGet {
+		return nil, fmt.Errorf("can't create loader %s: %w", name, ErrStaleNotSupported)
+	}
+	l.putter = putter
+	l.getter = getter
+
+	if !l.staleMode {
+		// the values are fresh for the default TTL of the cache
+		if ttlGetter, ok := c.(TTLGetter); ok {
+			l.softTTL = ttlGetter.TTL()
+		}
+	}
+	if l.staleIfError != nil {
+		l.grace += l.staleIfError.window
+	}
+
+	return l, nil
+}
+
+func (l *Loader) initXFetch(name string) (*Loader, error) {
+	if l.staleMode || l.staleIfError != nil {
+		return nil, fmt.Errorf("can't create loader %s: XFetch with stale entries: %w", name, ErrIncompatibleOptions)
+	}
+	if l.beta <= 0 {
+		return nil, fmt.Errorf("can't create loader %s: %w", name, ErrWrongBeta)
+	}
+	putter, okPut := l.cache.(ComputeTimePutter)
+	getter, okGet := l.cache.(ComputeTimeGetter)
+	if !okPut || !okGet {
+		return nil, fmt.Errorf("can't create loader %s: %w", name, ErrComputeTimeNotSupported)
+	}
+	l.computePutter = putter
+	l.computeGetter = getter
+	if ttlGetter, ok := l.cache.(TTLGetter); ok {
+		l.ttl = ttlGetter.TTL()
+	}
+
+	return l, nil
+}
+
+// GetOrLoad returns the value for the key from the cache, or loads it with LoadFunc and puts it into the cache.
+// In the stale-while-revalidate and refresh-ahead modes a stale value is returned immediately and refreshed in the background.
+// With XFetch the value may be loaded before its expiration, if such load fails, the cached value is returned.
+// If the cache is a Leaser (and no stale or XFetch mode is used), a miss takes a lease on the key
+// and the loaded value is put only if the key was not written or removed during the load.
+// The load errors are not cached.
+func (l *Loader) GetOrLoad(ctx context.Context, key interface{}) (interface{}, error) {
+	if l.xfetch {
+		v, expires, computeTime, ok := l.computeGetter.GetWithComputeTime(key)
+		if ok && !xfetch(l.clock.Now(), expires, computeTime, l.beta) {
+			return v, nil
+		}
+		loaded, err := l.loadShared(ctx, key, 0)
+		if err != nil && ok {
+			// the cached value has not expired yet
+			return v, nil
+		}
+		return loaded, err
+	}
+
+	if l.getter == nil && l.leaser != nil {
+		v, lease, ok := l.leaser.GetWithLease(key)
+		if ok {
+			return v, nil
+		}
+		return l.loadShared(ctx, key, lease)
+	}
+
+	if l.getter == nil {
+		if v, ok := l.cache.Get(key); ok {
+			return v, nil
+		}
+		return l.loadShared(ctx, key, 0)
+	}
+
+	v, stale, ok := l.getter.GetStale(key)
+	if ok && !stale {
+		return v, nil
+	}
+	if ok && l.staleMode {
+		l.refresh(ctx, key)
+		return v, nil
+	}
+
+	loaded, err := l.loadShared(ctx, key, 0)
+	if err != nil && ok && l.staleIfError.match(err) {
+		l.metrics.StaleOnErrorCount.Inc()
+		return v, nil
+	}
+	return loaded, err
+}
+
+// loadShared loads the key, joining the in-flight load of the same key if there is one.
+// The value is put with the lease, if it is not 0.
+func (l *Loader) loadShared(ctx context.Context, key interface{}, lease uint64) (interface{}, error) {
+	l.mu.Lock()
+	if c, ok := l.calls[key]; ok {
+		l.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.value, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c := &loadCall{done: make(chan struct{}), lease: lease}
+	l.calls[key] = c
+	l.mu.Unlock()
+
+	l.run(ctx, key, c)
+	return c.value, c.err
+}
+
+// refresh starts a background load of the stale key, unless the key is already being loaded
+// or the limit of the background refreshes is reached. The refresh is not canceled with the request context.
+func (l *Loader) refresh(ctx context.Context, key interface{}) {
+	l.mu.Lock()
+	if _, ok := l.calls[key]; ok {
+		l.mu.Unlock()
+		return
+	}
+	if l.refreshSem != nil {
+		select {
+		case l.refreshSem <- struct{}{}:
+		default:
+			l.mu.Unlock()
+			return
+		}
+	}
+	c := &loadCall{done: make(chan struct{})}
+	l.calls[key] = c
+	l.mu.Unlock()
+
+	l.metrics.RefreshCount.Inc()
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if l.refreshSem != nil {
+			defer func() { <-l.refreshSem }()
+		}
+
+		l.run(ctx, key, c)
+		if c.err != nil {
+			l.metrics.RefreshErrorCount.Inc()
+			l.logErrorf(ctx, "cache.Loader: failed to refresh key %v, got err: %s", key, c.err)
+		}
+	}()
+}
+
+func (l *Loader) run(ctx context.Context, key interface{}, c *loadCall) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.calls, key)
+		l.mu.Unlock()
+		close(c.done)
+	}()
+
+	start := l.clock.Now()
+	c.value, c.err = l.load(ctx, key)
+	if c.err != nil {
+		return
+	}
+	if l.xfetch {
+		l.computePutter.PutWithComputeTime(key, c.value, l.ttl, l.clock.Since(start))
+	} else if l.putter != nil {
+		l.putter.PutWithSoftTTL(key, c.value, l.softTTL, l.hardTTL())
+	} else if c.lease != 0 {
+		// the lease is invalid if the key was written or removed during the load, then the value is not put
+		_ = l.leaser.PutWithLease(key, c.value, c.lease)
+	} else {
+		l.cache.Put(key, c.value)
+	}
+}
+
+// hardTTL returns the TTL after which the stale values are dropped, 0 means no TTL.
+func (l *Loader) hardTTL() time.Duration {
+	if l.softTTL <= 0 {
+		return 0
+	}
+	return l.softTTL + l.grace
+}
+
+// staleIfError is the configuration of serving the stale values on errors (see WithStaleIfError).
+type staleIfError struct {
+	window time.Duration
+	codes  []codes.Code
+}
+
+// match checks if the stale value can be served instead of the error.
+func (s *staleIfError) match(err error) bool {
+	if s == nil {
+		return false
+	}
+	if len(s.codes) == 0 {
+		return true
+	}
+	code := status.Code(err)
+	for _, c := range s.codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// xfetch reports whether the value should be recomputed before its expiration, following the optimal probabilistic
+// early expiration (A. Vattani et al., "Optimal Probabilistic Cache Stampede Prevention"):
+// the value is expired early if now - computeTime * beta * ln(rand()) >= expires, with rand() in (0, 1].
+func xfetch(now, expires time.Time, computeTime time.Duration, beta float64) bool {
+	if expires.IsZero() || computeTime <= 0 {
+		return false
+	}
+	// 1 - rand() is in (0, 1], so the logarithm is finite
+	gap := -float64(computeTime) * beta * math.Log(1-rand.Float64())
+	if gap >= float64(math.MaxInt64) {
+		// the gap does not fit a time.Duration, the value is expired early anyway
+		return true
+	}
+	return !now.Add(time.Duration(gap)).Before(expires)
+}
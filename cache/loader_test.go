@@ -0,0 +1,387 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/lru"
+	"github.com/catalystgo/cache-go/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("load once", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		var calls atomic.Int32
+		release := make(chan struct{})
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			calls.Add(1)
+			<-release
+			return "value", nil
+		})
+		require.NoError(t, err)
+
+		// act
+		var wg sync.WaitGroup
+		results := make([]interface{}, 5)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = l.GetOrLoad(context.Background(), "key")
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		// assert
+		assert.Equal(t, int32(1), calls.Load())
+		for _, v := range results {
+			assert.Equal(t, "value", v)
+		}
+		v, ok := c.Get("key")
+		assert.True(t, ok)
+		assert.Equal(t, "value", v)
+	})
+
+	t.Run("error is not cached", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		loadErr := errors.New("load failed")
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			return nil, loadErr
+		})
+		require.NoError(t, err)
+
+		// act
+		_, err = l.GetOrLoad(context.Background(), "key")
+
+		// assert
+		require.ErrorIs(t, err, loadErr)
+		assert.False(t, c.Contains("key"))
+	})
+
+	t.Run("stale while revalidate", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		var calls atomic.Int32
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			return calls.Add(1), nil
+		}, cache.WithStaleWhileRevalidate(20*time.Millisecond, time.Minute))
+		require.NoError(t, err)
+
+		v, err := l.GetOrLoad(context.Background(), "key")
+		require.NoError(t, err)
+		require.Equal(t, int32(1), v)
+		time.Sleep(30 * time.Millisecond)
+
+		// act
+		var values []interface{}
+		for i := 0; i < 3; i++ {
+			v, err := l.GetOrLoad(context.Background(), "key")
+			require.NoError(t, err)
+			values = append(values, v)
+		}
+
+		// assert
+		assert.Equal(t, []interface{}{int32(1), int32(1), int32(1)}, values)
+		require.Eventually(t, func() bool {
+			v, ok := c.Get("key")
+			return ok && v == int32(2)
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("stale refresh error keeps value", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		failed := make(chan struct{}, 1)
+		var calls atomic.Int32
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			if calls.Add(1) > 1 {
+				return nil, errors.New("load failed")
+			}
+			return "value", nil
+		},
+			cache.WithStaleWhileRevalidate(10*time.Millisecond, time.Minute),
+			cache.WithLoaderErrorf(func(ctx context.Context, format string, args ...interface{}) {
+				failed <- struct{}{}
+			}),
+		)
+		require.NoError(t, err)
+
+		_, err = l.GetOrLoad(context.Background(), "key")
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		// act
+		v, err := l.GetOrLoad(context.Background(), "key")
+		<-failed
+
+		// assert
+		require.NoError(t, err)
+		assert.Equal(t, "value", v)
+		v, stale, ok := c.GetStale("key")
+		assert.True(t, ok)
+		assert.True(t, stale)
+		assert.Equal(t, "value", v)
+	})
+
+	t.Run("stale if error", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 20*time.Millisecond)
+		require.NoError(t, err)
+
+		var calls atomic.Int32
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			switch calls.Add(1) {
+			case 1:
+				return "value", nil
+			case 2:
+				return nil, status.Error(codes.Unavailable, "backend is down")
+			default:
+				return nil, status.Error(codes.NotFound, "not found")
+			}
+		}, cache.WithStaleIfError(time.Minute, codes.Unavailable))
+		require.NoError(t, err)
+
+		_, err = l.GetOrLoad(context.Background(), "key")
+		require.NoError(t, err)
+		time.Sleep(30 * time.Millisecond)
+
+		// act
+		staleValue, staleErr := l.GetOrLoad(context.Background(), "key")
+		_, notFoundErr := l.GetOrLoad(context.Background(), "key")
+
+		// assert
+		require.NoError(t, staleErr)
+		assert.Equal(t, "value", staleValue)
+		assert.Equal(t, codes.NotFound, status.Code(notFoundErr))
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("refresh ahead", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		var calls atomic.Int32
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			return calls.Add(1), nil
+		}, cache.WithRefreshAhead(100*time.Millisecond, 0.5))
+		require.NoError(t, err)
+
+		_, err = l.GetOrLoad(context.Background(), "key")
+		require.NoError(t, err)
+
+		// act
+		early, err := l.GetOrLoad(context.Background(), "key")
+		require.NoError(t, err)
+		time.Sleep(60 * time.Millisecond)
+		late, err := l.GetOrLoad(context.Background(), "key")
+		require.NoError(t, err)
+
+		// assert
+		assert.Equal(t, int32(1), early)
+		assert.Equal(t, int32(1), late)
+		require.Eventually(t, func() bool {
+			v, ok := c.Get("key")
+			return ok && v == int32(2)
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("refresh concurrency", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		var calls atomic.Int32
+		release := make(chan struct{})
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			if calls.Add(1) > 2 {
+				<-release
+			}
+			return key, nil
+		}, cache.WithStaleWhileRevalidate(10*time.Millisecond, time.Minute), cache.WithRefreshConcurrency(1))
+		require.NoError(t, err)
+
+		_, err = l.GetOrLoad(context.Background(), "a")
+		require.NoError(t, err)
+		_, err = l.GetOrLoad(context.Background(), "b")
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		// act
+		_, err = l.GetOrLoad(context.Background(), "a")
+		require.NoError(t, err)
+		_, err = l.GetOrLoad(context.Background(), "b")
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		// assert
+		assert.Equal(t, int32(3), calls.Load())
+		close(release)
+	})
+
+	t.Run("wrong refresh ratio", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		// act
+		_, err = cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			return nil, nil
+		}, cache.WithRefreshAhead(time.Second, 1))
+
+		// assert
+		require.ErrorIs(t, err, cache.ErrWrongRefreshRatio)
+	})
+
+	t.Run("xfetch", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range []struct {
+			name  string
+			beta  float64
+			calls int32
+		}{
+			{name: "far from expiration", beta: 1e-9, calls: 1},
+			{name: "eager", beta: 1e9, calls: 2},
+		} {
+			c, err := lru.NewCache("test", 10, time.Minute)
+			require.NoError(t, err)
+
+			var calls atomic.Int32
+			l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+				time.Sleep(time.Millisecond)
+				return calls.Add(1), nil
+			}, cache.WithXFetch(tc.beta))
+			require.NoError(t, err)
+
+			_, err = l.GetOrLoad(context.Background(), "key")
+			require.NoError(t, err)
+
+			// act
+			v, err := l.GetOrLoad(context.Background(), "key")
+
+			// assert
+			require.NoError(t, err, tc.name)
+			assert.Equal(t, tc.calls, v, tc.name)
+			assert.Equal(t, tc.calls, calls.Load(), tc.name)
+		}
+	})
+
+	t.Run("xfetch clock", func(t *testing.T) {
+		t.Parallel()
+
+		clock := cache.NewFakeClock(time.Now())
+		c, err := lru.NewCache("test", 10, time.Minute, lru.WithClock(clock))
+		require.NoError(t, err)
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			clock.Advance(10 * time.Second)
+			return "value", nil
+		}, cache.WithXFetch(1), cache.WithLoaderClock(clock))
+		require.NoError(t, err)
+
+		// act
+		_, err = l.GetOrLoad(context.Background(), "key")
+
+		// assert
+		require.NoError(t, err)
+		_, expires, computeTime, ok := c.GetWithComputeTime("key")
+		require.True(t, ok)
+		assert.Equal(t, 10*time.Second, computeTime)
+		assert.Equal(t, clock.Now().Add(time.Minute), expires)
+	})
+
+	t.Run("xfetch with stale entries", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := lru.NewCache("test", 10, time.Minute)
+		require.NoError(t, err)
+
+		// act
+		_, err = cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			return nil, nil
+		}, cache.WithXFetch(1), cache.WithStaleIfError(time.Minute))
+
+		// assert
+		require.ErrorIs(t, err, cache.ErrIncompatibleOptions)
+	})
+
+	t.Run("stale not supported", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		c := mock.NewMockCache(ctrl)
+
+		// act
+		_, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			return nil, nil
+		}, cache.WithStaleWhileRevalidate(time.Second, time.Second))
+
+		// assert
+		require.ErrorIs(t, err, cache.ErrStaleNotSupported)
+	})
+	t.Run("lease rejects stale set", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewLeaseCache(base)
+		require.NoError(t, err)
+
+		loading := make(chan struct{})
+		release := make(chan struct{})
+		l, err := cache.NewLoader(c, func(ctx context.Context, key interface{}) (interface{}, error) {
+			close(loading)
+			<-release
+			return "old", nil
+		})
+		require.NoError(t, err)
+
+		// act
+		done := make(chan interface{})
+		go func() {
+			v, _ := l.GetOrLoad(context.Background(), "key")
+			done <- v
+		}()
+		<-loading
+		c.Remove("key")
+		close(release)
+		v := <-done
+
+		// assert
+		assert.Equal(t, "old", v)
+		assert.False(t, c.Contains("key"))
+	})
+}
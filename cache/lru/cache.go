@@ -3,6 +3,8 @@ package lru
 import (
 	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/catalystgo/cache-go/cache"
@@ -11,9 +13,23 @@ import (
 )
 
 var (
-	_ cache.NamedCache    = &Cache{}
-	_ cache.WithTTLPutter = &Cache{}
-	_ io.Closer           = &Cache{}
+	_ cache.NamedCache        = &Cache{}
+	_ cache.WithTTLPutter     = &Cache{}
+	_ cache.CostPutter        = &Cache{}
+	_ cache.SoftTTLPutter     = &Cache{}
+	_ cache.StaleGetter       = &Cache{}
+	_ cache.TTLGetter         = &Cache{}
+	_ cache.ComputeTimePutter = &Cache{}
+	_ cache.ComputeTimeGetter = &Cache{}
+	_ cache.SlidingTTLPutter  = &Cache{}
+	_ cache.MetaGetter        = &Cache{}
+	_ cache.BatchGetter       = &Cache{}
+	_ cache.BatchPutter       = &Cache{}
+	_ cache.PrefixRemover     = &Cache{}
+	_ cache.Pinner            = &Cache{}
+	_ cache.AtomicUpdater     = &Cache{}
+	_ cache.Versioner         = &Cache{}
+	_ io.Closer               = &Cache{}
 )
 
 // Cache is a structure representing a wrapper over LRU cache (hashicorp).
@@ -25,11 +41,80 @@ type Cache struct {
 	name    string
 	cap     int
 	ttl     time.Duration
+	jitter  *cache.Jitter
+	// keys is the index of the string keys, nil if it is disabled (see WithKeyIndex)
+	keys    *cache.KeyIndex
+	clock   cache.Clock
+	sliding bool
+	// version is the version of the last written entry, it is incremented under mu (see PutIfVersion)
+	version atomic.Uint64
+
+	// mu serializes the writes of the cache, so the atomic operations see every write and the total cost stays accurate.
+	mu       sync.Mutex
+	maxCost  int64
+	cost     atomic.Int64
+	estimate cache.SizeEstimator
+
+	// pinMu guards the pinned segment, it is locked after mu.
+	pinMu sync.RWMutex
+	// pinned is the segment of the entries which are not evicted by the capacity (see PutPinned)
+	pinned    map[interface{}]*entry
+	pinnedCap int
 }
 
 type entry struct {
 	value   interface{}
 	expires time.Time
+	stale   time.Time
+	cost    int64
+	// computeTime is the time it took to compute the value (see PutWithComputeTime)
+	computeTime time.Duration
+	// sliding is the inactivity period after which the entry expires, 0 means the expiration is fixed (expires)
+	sliding time.Duration
+	// version is the version of the write (see Cache.PutIfVersion)
+	version uint64
+	// inserted is the time the entry was put
+	inserted time.Time
+	// accessed is the time of the last read (or the put) in unix nanoseconds, it extends the expiration of the sliding entries
+	accessed atomic.Int64
+	// hits is the number of the reads of the entry
+	hits atomic.Uint64
+}
+
+// deadline returns the hard expiration time of the entry, the zero time means no TTL.
+func (e *entry) deadline() time.Time {
+	if e.sliding > 0 {
+		return time.Unix(0, e.accessed.Load()+int64(e.sliding))
+	}
+	return e.expires
+}
+
+// alive reports whether the hard TTL of the entry has not passed.
+func (e *entry) alive(now time.Time) bool {
+	deadline := e.deadline()
+	return deadline.IsZero() || now.Before(deadline)
+}
+
+// touch records the read of the entry, it extends the expiration of the sliding entry.
+func (e *entry) touch(now time.Time) {
+	e.accessed.Store(now.UnixNano())
+	e.hits.Add(1)
+}
+
+// meta returns the metadata of the entry.
+func (e *entry) meta() cache.EntryMeta {
+	return cache.EntryMeta{
+		Value:    e.value,
+		Inserted: e.inserted,
+		Expires:  e.deadline(),
+		Accessed: time.Unix(0, e.accessed.Load()),
+		Hits:     e.hits.Load(),
+	}
+}
+
+// fresh reports whether neither the soft nor the hard TTL of the entry has passed.
+func (e *entry) fresh(now time.Time) bool {
+	return e.alive(now) && (e.stale.IsZero() || now.Before(e.stale))
 }
 
 const (
@@ -38,35 +123,72 @@ const (
 
 // NewCache creates a new LRU cache with capacity and ttl.
 // If ttl == 0, the cache will be without ttl.
-func NewCache(name string, cap int, ttl time.Duration) (*Cache, error) {
-	return NewCacheWithEvictCallback(name, cap, ttl, nil)
+func NewCache(name string, cap int, ttl time.Duration, opts ...Option) (*Cache, error) {
+	return NewCacheWithEvictCallback(name, cap, ttl, nil, opts...)
 }
 
 // NewCacheWithEvictCallback is NewCache + setting a function to be called when an item is evicted from the cache.
 // `onEvict` can be `nil`, in which case the function will not be called.
+// `onEvict` is called under the write lock of the cache, so it must not write to the cache.
 //
 // Use WrapOnEvictWithUnwrapper to access your original item in the callback function.
 // Without unwrapping using WrapOnEvictWithUnwrapper, the function will be called with typeof(value) == `entry`.
-func NewCacheWithEvictCallback(name string, cap int, ttl time.Duration, onEvict func(key interface{}, value interface{})) (*Cache, error) {
+func NewCacheWithEvictCallback(
+	name string,
+	cap int,
+	ttl time.Duration,
+	onEvict func(key interface{}, value interface{}),
+	opts ...Option,
+) (*Cache, error) {
+	oo := &options{
+		estimate: cache.EstimateSize,
+		clock:    cache.SystemClock,
+	}
+	for _, o := range opts {
+		o(oo)
+	}
 	if cap <= 0 {
 		return nil, fmt.Errorf("can't create cache %s: %w", name, cache.ErrWrongCapacity)
 	}
 	if ttl < 0 {
 		return nil, fmt.Errorf("can't create cache %s: %w", name, cache.ErrWrongTTL)
 	}
-
-	lruCache, err := lru.NewWithEvict(cap, onEvict)
+	if oo.maxCost < 0 {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, cache.ErrWrongCost)
+	}
+	if oo.pinnedCap < 0 {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, cache.ErrWrongPinnedCap)
+	}
+	if oo.pinnedCap == 0 {
+		oo.pinnedCap = cap
+	}
+	jitter, err := cache.NewJitter(oo.jitter, oo.jitterPercent, oo.randSource)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("can't create cache %s: %w", name, err)
 	}
+
 	c := &Cache{
-		Cache:   lruCache,
-		name:    name,
-		cap:     cap,
-		ttl:     ttl,
-		close:   make(chan struct{}),
-		metrics: metrics.NewCacheMetrics(name),
+		name:      name,
+		cap:       cap,
+		ttl:       ttl,
+		close:     make(chan struct{}),
+		metrics:   metrics.NewCacheMetrics(name),
+		jitter:    jitter,
+		clock:     oo.clock,
+		sliding:   oo.sliding,
+		maxCost:   oo.maxCost,
+		estimate:  oo.estimate,
+		pinned:    make(map[interface{}]*entry),
+		pinnedCap: oo.pinnedCap,
+	}
+	if oo.keyIndex {
+		c.keys = cache.NewKeyIndex()
 	}
+	lruCache, err := lru.NewWithEvict(cap, c.onEvict(onEvict))
+	if err != nil {
+		return nil, err
+	}
+	c.Cache = lruCache
 
 	go c.stats()
 
@@ -78,8 +200,23 @@ func (c *Cache) Cap() int {
 	return c.cap
 }
 
+// TTL returns the default TTL of the cache, 0 means no TTL.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
 // Clear completely clears the cache.
 func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pinMu.Lock()
+	for key := range c.pinned {
+		c.keys.Delete(key)
+	}
+	c.pinned = make(map[interface{}]*entry)
+	c.pinMu.Unlock()
+
 	c.Cache.Purge()
 }
 
@@ -108,33 +245,156 @@ func (c *Cache) Put(key, value interface{}) {
 
 // PutWithTTL adds a key-value pair to the cache with a specified TTL.
 // If ttl <= 0, no TTL is added.
+// If the cache has a cost budget (see WithMaxCost), the cost of the value is computed by the size estimator.
 func (c *Cache) PutWithTTL(key, value interface{}, ttl time.Duration) {
-	start := time.Now()
+	e := c.newEntry(value, 0, ttl, c.sliding)
+	e.cost = c.costOf(value)
+	c.put(key, e)
+}
+
+// PutWithCost adds a key-value pair with the specified cost to the cache.
+// It uses the default TTL specified in NewCache.
+// The cost is taken into account only if the cache has a cost budget (see WithMaxCost).
+func (c *Cache) PutWithCost(key, value interface{}, cost int64) {
+	e := c.newEntry(value, 0, c.ttl, c.sliding)
+	e.cost = cost
+	c.put(key, e)
+}
+
+// PutWithComputeTime adds a key-value pair to the cache with a specified TTL and the time it took to compute the value,
+// which is used by the probabilistic early expiration (see cache.WithXFetch).
+func (c *Cache) PutWithComputeTime(key, value interface{}, ttl, computeTime time.Duration) {
+	e := c.newEntry(value, 0, ttl, c.sliding)
+	e.cost = c.costOf(value)
+	e.computeTime = computeTime
+	c.put(key, e)
+}
+
+// PutWithSoftTTL adds a key-value pair to the cache with the soft and hard TTLs.
+// After softTTL the entry is stale: Get and Peek treat it as expired, GetStale returns it until hardTTL.
+// If softTTL <= 0, the entry never becomes stale. If hardTTL <= 0, no hard TTL is added.
+// The hard TTL is fixed even if the cache has the sliding TTL mode.
+func (c *Cache) PutWithSoftTTL(key, value interface{}, softTTL, hardTTL time.Duration) {
+	e := c.newEntry(value, softTTL, hardTTL, false)
+	e.cost = c.costOf(value)
+	c.put(key, e)
+}
+
+// PutWithSlidingTTL adds a key-value pair to the cache, which expires after ttl of inactivity:
+// Get extends the expiration, Peek does not. It overrides the TTL mode of the cache (see WithSlidingTTL) for the entry.
+// If ttl <= 0, no TTL is added.
+func (c *Cache) PutWithSlidingTTL(key, value interface{}, ttl time.Duration) {
+	e := c.newEntry(value, 0, ttl, true)
+	e.cost = c.costOf(value)
+	c.put(key, e)
+}
+
+// PutWithFixedTTL adds a key-value pair to the cache, which expires after ttl since the write.
+// It overrides the TTL mode of the cache (see WithSlidingTTL) for the entry.
+// If ttl <= 0, no TTL is added.
+func (c *Cache) PutWithFixedTTL(key, value interface{}, ttl time.Duration) {
+	e := c.newEntry(value, 0, ttl, false)
+	e.cost = c.costOf(value)
+	c.put(key, e)
+}
+
+// newEntry creates an entry which expires after ttl and becomes stale after softTTL, the jitter of the cache is added to both.
+// A sliding entry expires after ttl since the last read.
+func (c *Cache) newEntry(value interface{}, softTTL, ttl time.Duration, sliding bool) *entry {
+	now := c.clock.Now()
+
+	// the same jitter is added to the soft TTL to keep the stale period
+	jitter := c.jitter.Duration(ttl)
+	ttl += jitter
+	if softTTL > 0 {
+		softTTL += jitter
+	}
+
+	e := &entry{value: value, inserted: now}
+	e.accessed.Store(now.UnixNano())
+	if ttl > 0 && sliding {
+		e.sliding = ttl
+	} else if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	if softTTL > 0 && (ttl <= 0 || softTTL < ttl) {
+		e.stale = now.Add(softTTL)
+	}
+	return e
+}
+
+// costOf returns the cost of the value if the cache has a cost budget.
+func (c *Cache) costOf(value interface{}) int64 {
+	if c.maxCost <= 0 {
+		return 0
+	}
+	return c.estimate(value)
+}
+
+func (c *Cache) put(key interface{}, e *entry) {
+	start := c.clock.Now()
 
 	defer func() {
-		c.metrics.ResponseTimeSet.Observe(metrics.SinceSeconds(start))
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
 	}()
 
-	var expires time.Time
-	if ttl > 0 {
-		expires = time.Now().Add(ttl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addLocked(key, e)
+}
+
+// addLocked adds the entry to the cache, c.mu must be held.
+// The entry of a pinned key replaces the pinned one.
+func (c *Cache) addLocked(key interface{}, e *entry) {
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+
+	// the version is given under the lock, so the versions of the writes of a key only grow
+	e.version = c.version.Add(1)
+	if _, ok := c.pinned[key]; ok {
+		c.pinned[key] = e
+		return
+	}
+	c.addUnpinnedLocked(key, e)
+}
+
+// addUnpinnedLocked adds the entry to the regular segment, c.pinMu must be held.
+func (c *Cache) addUnpinnedLocked(key interface{}, e *entry) {
+	if c.maxCost <= 0 {
+		c.Cache.Add(key, e)
+		c.keys.Insert(key)
+		return
 	}
 
-	c.Cache.Add(key, &entry{
-		expires: expires,
-		value:   value,
-	})
+	cost := e.cost
+	if cost > c.maxCost {
+		// the value can never fit, drop the previous one to not serve stale data
+		c.Cache.Remove(key)
+		return
+	}
+	// Add replaces the value of an existing key without calling onEvict
+	if old, ok := c.Cache.Peek(key); ok {
+		c.cost.Add(-old.(*entry).cost)
+	}
+	c.Cache.Add(key, e)
+	c.keys.Insert(key)
+	c.cost.Add(cost)
+	for c.cost.Load() > c.maxCost {
+		if _, _, ok := c.Cache.RemoveOldest(); !ok {
+			break
+		}
+	}
 }
 
 // Get retrieves a value by a specific key from the cache,
 // and returns a boolean indicating whether the value was found.
 // If the TTL of the key has expired, it returns nil and false.
 func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
-	start := time.Now()
+	start := c.clock.Now()
 	expired := false
 
 	defer func() {
-		c.metrics.ResponseTimeGet.Observe(metrics.SinceSeconds(start))
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
 		if ok {
 			c.metrics.HitCount.Inc()
 		} else if expired {
@@ -144,64 +404,548 @@ func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
 		}
 	}()
 
-	v, ok := c.Cache.Get(key)
+	e, ok := c.lookup(key, true)
 	if ok {
-		if v.(*entry).expires.IsZero() || time.Now().Before(v.(*entry).expires) {
-			return v.(*entry).value, true
+		now := c.clock.Now()
+		if e.fresh(now) {
+			e.touch(now)
+			return e.value, true
 		}
 		expired = true
 	}
 	return nil, false
 }
 
+// GetStale retrieves a value by key like Get, but also returns the entries which soft TTL has passed
+// (see PutWithSoftTTL), stale reports whether the soft TTL of the entry has passed.
+func (c *Cache) GetStale(key interface{}) (value interface{}, stale bool, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok && stale {
+			c.metrics.StaleCount.Inc()
+		} else if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	e, ok := c.lookup(key, true)
+	if ok {
+		now := c.clock.Now()
+		if e.alive(now) {
+			e.touch(now)
+			return e.value, !e.fresh(now), true
+		}
+		expired = true
+	}
+	return nil, false, false
+}
+
+// GetWithComputeTime retrieves a value by key like Get, with its expiration time and the time it took to compute it
+// (see PutWithComputeTime).
+func (c *Cache) GetWithComputeTime(key interface{}) (value interface{}, expires time.Time, computeTime time.Duration, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	e, ok := c.lookup(key, true)
+	if ok {
+		now := c.clock.Now()
+		if e.fresh(now) {
+			e.touch(now)
+			return e.value, e.deadline(), e.computeTime, true
+		}
+		expired = true
+	}
+	return nil, time.Time{}, 0, false
+}
+
+// GetWithMeta retrieves a value by key like Get, with the metadata of the entry: the insertion, the expiration
+// and the last access time and the number of the reads, including this one.
+func (c *Cache) GetWithMeta(key interface{}) (meta cache.EntryMeta, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	e, ok := c.lookup(key, true)
+	if ok {
+		now := c.clock.Now()
+		if e.fresh(now) {
+			e.touch(now)
+			return e.meta(), true
+		}
+		expired = true
+	}
+	return cache.EntryMeta{}, false
+}
+
+// PeekMeta returns the metadata of the entry like GetWithMeta, without updating the access time and the number of the reads.
+func (c *Cache) PeekMeta(key interface{}) (meta cache.EntryMeta, ok bool) {
+	e, ok := c.lookup(key, false)
+	if ok && e.fresh(c.clock.Now()) {
+		return e.meta(), true
+	}
+	return cache.EntryMeta{}, false
+}
+
 // Peek retrieves a value by key without updating the access time,
 // and returns a boolean indicating whether the value was found.
 // If the TTL of the key has expired, it returns nil and false.
 func (c *Cache) Peek(key interface{}) (value interface{}, ok bool) {
-	v, ok := c.Cache.Peek(key)
-	if ok && (v.(*entry).expires.IsZero() || time.Now().Before(v.(*entry).expires)) {
-		return v.(*entry).value, true
+	e, ok := c.lookup(key, false)
+	if ok && e.fresh(c.clock.Now()) {
+		return e.value, true
 	}
 	return nil, false
 }
 
 // Remove removes a value by key from the cache.
 func (c *Cache) Remove(key interface{}) {
-	start := time.Now()
+	start := c.clock.Now()
 
 	defer func() {
-		c.metrics.ResponseTimeDelete.Observe(metrics.SinceSeconds(start))
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
 	}()
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removePinned(key)
 	c.Cache.Remove(key)
 }
 
+// GetMany retrieves the values by keys like Get, it returns the found values by key
+// and the keys which are missing or expired, in the order of keys. The metrics are recorded once per batch.
+func (c *Cache) GetMany(keys []interface{}) (hits map[interface{}]interface{}, missing []interface{}) {
+	start := c.clock.Now()
+	hit, expired := 0, 0
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		c.metrics.HitCount.Add(float64(hit))
+		c.metrics.ExpiredCount.Add(float64(expired))
+		c.metrics.MissCount.Add(float64(len(missing) - expired))
+	}()
+
+	hits = make(map[interface{}]interface{}, len(keys))
+	for _, key := range keys {
+		if e, ok := c.lookup(key, true); ok {
+			if e.fresh(start) {
+				e.touch(start)
+				hits[key] = e.value
+				hit++
+				continue
+			}
+			expired++
+		}
+		missing = append(missing, key)
+	}
+	return hits, missing
+}
+
+// PutMany adds the key-value pairs to the cache with the default TTL, in the order of items.
+// The metrics are recorded once per batch.
+func (c *Cache) PutMany(items []cache.KeyValue) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	entries := make([]*entry, len(items))
+	for i, item := range items {
+		entries[i] = c.newEntry(item.Value, 0, c.ttl, c.sliding)
+		entries[i].cost = c.costOf(item.Value)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, item := range items {
+		c.addLocked(item.Key, entries[i])
+	}
+}
+
+// RemoveMany removes the values by keys from the cache, the metrics are recorded once per batch.
+func (c *Cache) RemoveMany(keys []interface{}) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		c.removePinned(key)
+		c.Cache.Remove(key)
+	}
+}
+
+// RemoveByPrefix removes the entries which keys are strings with the prefix, it returns the number of removed entries.
+// With WithKeyIndex the keys are found in the index, otherwise all keys are scanned.
+func (c *Cache) RemoveByPrefix(prefix string) int {
+	var keys []interface{}
+	if c.keys != nil {
+		keys = c.keys.KeysWithPrefix(prefix)
+	} else {
+		keys = cache.FilterKeys(c.Keys(), cache.HasPrefix(prefix))
+	}
+	c.RemoveMany(keys)
+	return len(keys)
+}
+
+// RemoveMatching removes the entries which keys match the predicate, it returns the number of removed entries.
+// All keys are scanned.
+func (c *Cache) RemoveMatching(match func(key interface{}) bool) int {
+	keys := cache.FilterKeys(c.Keys(), match)
+	c.RemoveMany(keys)
+	return len(keys)
+}
+
+// PutIfAbsent puts the value with the default TTL if the key is absent, expired or stale,
+// it returns the current value of the key and whether it was loaded.
+// The atomic operations (PutIfAbsent, CompareAndSwap and Update) are serialized with the other writes by the cache lock.
+func (c *Cache) PutIfAbsent(key, value interface{}) (actual interface{}, loaded bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.current(key, start); ok {
+		return old, true
+	}
+	c.addLocked(key, c.defaultEntry(value))
+	return value, false
+}
+
+// CompareAndSwap replaces the value of the key with newValue and the default TTL if the current value equals oldValue,
+// it reports whether the value was swapped. The absent, expired and stale keys are not swapped, the values must be comparable.
+func (c *Cache) CompareAndSwap(key, oldValue, newValue interface{}) bool {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.current(key, start); !ok || old != oldValue {
+		return false
+	}
+	c.addLocked(key, c.defaultEntry(newValue))
+	return true
+}
+
+// Update calls fn with the current value of the key (exists is false if it is absent, expired or stale)
+// and puts the returned value with the default TTL if keep is true, otherwise it removes the key.
+// It returns the new value and keep. fn is called under the cache lock, so it must not call the cache.
+func (c *Cache) Update(
+	key interface{},
+	fn func(old interface{}, exists bool) (value interface{}, keep bool),
+) (value interface{}, ok bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, exists := c.current(key, start)
+	value, ok = fn(old, exists)
+	if !ok {
+		c.removePinned(key)
+		c.Cache.Remove(key)
+		return nil, false
+	}
+	c.addLocked(key, c.defaultEntry(value))
+	return value, true
+}
+
+// GetVersioned retrieves a value by key like Get, with the version of the entry (see PutIfVersion).
+func (c *Cache) GetVersioned(key interface{}) (value interface{}, version uint64, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	e, ok := c.lookup(key, true)
+	if ok {
+		now := c.clock.Now()
+		if e.fresh(now) {
+			e.touch(now)
+			return e.value, e.version, true
+		}
+		expired = true
+	}
+	return nil, 0, false
+}
+
+// PutIfVersion puts the value with the default TTL if the version of the key is expectedVersion,
+// 0 means the key is absent, expired or stale. Otherwise it returns *cache.VersionMismatchError.
+// It is serialized like the atomic operations (see PutIfAbsent).
+func (c *Cache) PutIfVersion(key, value interface{}, expectedVersion uint64) error {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var version uint64
+	if e, ok := c.lookup(key, false); ok && e.fresh(start) {
+		version = e.version
+	}
+	if version != expectedVersion {
+		return &cache.VersionMismatchError{Key: key, Expected: expectedVersion, Actual: version}
+	}
+	c.addLocked(key, c.defaultEntry(value))
+	return nil
+}
+
+// current returns the fresh value of the key without updating the recency.
+func (c *Cache) current(key interface{}, now time.Time) (interface{}, bool) {
+	if e, ok := c.lookup(key, false); ok && e.fresh(now) {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// defaultEntry creates the entry of the value with the default TTL and the sliding mode of the cache, like Put.
+func (c *Cache) defaultEntry(value interface{}) *entry {
+	e := c.newEntry(value, 0, c.ttl, c.sliding)
+	e.cost = c.costOf(value)
+	return e
+}
+
 // Name returns the cache name.
 func (c *Cache) Name() string {
 	return c.name
 }
 
-// Keys returns a list of saved keys.
+// Keys returns a list of saved keys, the pinned keys go last.
 func (c *Cache) Keys() []interface{} {
-	return c.Cache.Keys()
+	keys := c.Cache.Keys()
+
+	c.pinMu.RLock()
+	defer c.pinMu.RUnlock()
+
+	for key := range c.pinned {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Len returns the number of the items in the cache, including the pinned ones.
+func (c *Cache) Len() int {
+	c.pinMu.RLock()
+	defer c.pinMu.RUnlock()
+
+	return c.Cache.Len() + len(c.pinned)
+}
+
+// Contains checks for the presence of a key in the cache without updating the recency, including the pinned keys.
+func (c *Cache) Contains(key interface{}) bool {
+	c.pinMu.RLock()
+	_, ok := c.pinned[key]
+	c.pinMu.RUnlock()
+
+	return ok || c.Cache.Contains(key)
+}
+
+// PutPinned puts a key-value pair into the pinned segment with the default TTL: the entry is never evicted
+// by the capacity or the cost budget, but it still expires and is removed by Remove and Clear.
+// The puts of a pinned key keep it pinned until Unpin. The regular entry of the key is removed (calling the evict callback).
+// It returns cache.ErrPinnedFull if the pinned segment is full (see WithPinnedCap), the expired pinned entries are dropped first.
+func (c *Cache) PutPinned(key, value interface{}) error {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	e := c.newEntry(value, 0, c.ttl, c.sliding)
+	e.cost = c.costOf(value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+
+	if _, ok := c.pinned[key]; !ok && len(c.pinned) >= c.pinnedCap {
+		c.dropExpiredPinnedLocked(start)
+		if len(c.pinned) >= c.pinnedCap {
+			return fmt.Errorf("can't pin the key in cache %s: %w", c.name, cache.ErrPinnedFull)
+		}
+	}
+	c.Cache.Remove(key)
+	e.version = c.version.Add(1)
+	c.pinned[key] = e
+	c.keys.Insert(key)
+	return nil
+}
+
+// Unpin moves the pinned entry of the key to the regular segment, where it is evicted as usual.
+// It reports whether the key was pinned, an expired entry is dropped.
+func (c *Cache) Unpin(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+
+	e, ok := c.pinned[key]
+	if !ok {
+		return false
+	}
+	delete(c.pinned, key)
+	if e.alive(c.clock.Now()) {
+		c.addUnpinnedLocked(key, e)
+	} else {
+		c.keys.Delete(key)
+	}
+	return true
+}
+
+// PinnedLen returns the number of the pinned entries.
+func (c *Cache) PinnedLen() int {
+	c.pinMu.RLock()
+	defer c.pinMu.RUnlock()
+
+	return len(c.pinned)
+}
+
+// lookup returns the entry of the key from the pinned segment or the regular one,
+// get marks the regular entry as recently used.
+func (c *Cache) lookup(key interface{}, get bool) (*entry, bool) {
+	c.pinMu.RLock()
+	e, ok := c.pinned[key]
+	c.pinMu.RUnlock()
+	if ok {
+		return e, true
+	}
+
+	var v interface{}
+	if get {
+		v, ok = c.Cache.Get(key)
+	} else {
+		v, ok = c.Cache.Peek(key)
+	}
+	if !ok {
+		return nil, false
+	}
+	return v.(*entry), true
+}
+
+// removePinned removes the key from the pinned segment.
+func (c *Cache) removePinned(key interface{}) {
+	c.pinMu.Lock()
+	defer c.pinMu.Unlock()
+
+	if _, ok := c.pinned[key]; ok {
+		delete(c.pinned, key)
+		c.keys.Delete(key)
+	}
+}
+
+// dropExpiredPinnedLocked removes the expired entries from the pinned segment, c.pinMu must be held.
+func (c *Cache) dropExpiredPinnedLocked(now time.Time) {
+	for key, e := range c.pinned {
+		if !e.alive(now) {
+			delete(c.pinned, key)
+			c.keys.Delete(key)
+		}
+	}
 }
 
 // SetCap sets the capacity of the cache to cap.
 func (c *Cache) SetCap(cap int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	_ = c.Resize(cap)
 	c.cap = cap
 
 	return nil
 }
 
+// MaxCost returns the cost budget of the cache, 0 means the cache is limited only by the capacity.
+func (c *Cache) MaxCost() int64 {
+	return c.maxCost
+}
+
+// Cost returns the total cost of the items in the cache.
+func (c *Cache) Cost() int64 {
+	return c.cost.Load()
+}
+
+// onEvict keeps the total cost in sync with the items removed by the underlying cache.
+func (c *Cache) onEvict(orig func(key interface{}, value interface{})) func(key interface{}, value interface{}) {
+	return func(key interface{}, value interface{}) {
+		if ent, ok := value.(*entry); ok && ent.cost != 0 {
+			c.cost.Add(-ent.cost)
+		}
+		c.keys.Delete(key)
+		if orig != nil {
+			orig(key, value)
+		}
+	}
+}
+
 func (c *Cache) stats() {
 	for {
 		select {
-		case <-time.After(calcItemNumberInterval):
+		case <-c.clock.After(calcItemNumberInterval):
 		case <-c.close:
 			return
 		}
 		c.metrics.ItemNumber.Set(float64(c.Len()))
+		if c.maxCost > 0 {
+			c.metrics.TotalCost.Set(float64(c.Cost()))
+		}
 	}
 }
 
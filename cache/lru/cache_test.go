@@ -1,9 +1,12 @@
 package lru_test
 
 import (
+	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/catalystgo/cache-go/cache"
 	"github.com/catalystgo/cache-go/cache/lru"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -94,3 +97,492 @@ func TestCache_Keys_ShouldOK(t *testing.T) {
 	// assert
 	assert.Equal(t, wantKeys, gotKeys)
 }
+
+func TestCache_WithMaxCost_ShouldEvictByCost(t *testing.T) {
+	c, err := lru.NewCache("test", 100, 0, lru.WithMaxCost(10))
+	require.NoError(t, err)
+
+	c.PutWithCost(1, 1, 4)
+	c.PutWithCost(2, 2, 4)
+
+	// act
+	c.PutWithCost(3, 3, 4)
+
+	// assert
+	assert.False(t, c.Contains(1))
+	assert.True(t, c.Contains(2))
+	assert.True(t, c.Contains(3))
+	assert.Equal(t, int64(8), c.Cost())
+}
+
+func TestCache_WithMaxCost_UpdateAndRemove_ShouldKeepCost(t *testing.T) {
+	c, err := lru.NewCache("test", 100, 0, lru.WithMaxCost(10))
+	require.NoError(t, err)
+
+	c.PutWithCost(1, 1, 4)
+	c.PutWithCost(2, 2, 4)
+
+	// act
+	c.PutWithCost(1, 1, 2)
+	c.Remove(2)
+
+	// assert
+	assert.Equal(t, int64(2), c.Cost())
+	c.Clear()
+	assert.Equal(t, int64(0), c.Cost())
+}
+
+func TestCache_WithMaxCost_TooBigValue_ShouldNotBeStored(t *testing.T) {
+	c, err := lru.NewCache("test", 100, 0, lru.WithMaxCost(10))
+	require.NoError(t, err)
+
+	c.PutWithCost(1, 1, 4)
+
+	// act
+	c.PutWithCost(2, 2, 11)
+
+	// assert
+	assert.True(t, c.Contains(1))
+	assert.False(t, c.Contains(2))
+	assert.Equal(t, int64(4), c.Cost())
+}
+
+func TestCache_WithSizeEstimator_ShouldUseItOnPut(t *testing.T) {
+	c, err := lru.NewCache("test", 100, 0,
+		lru.WithMaxCost(100),
+		lru.WithSizeEstimator(func(value interface{}) int64 { return int64(len(value.(string))) }),
+	)
+	require.NoError(t, err)
+
+	// act
+	c.Put(1, "hello")
+	c.Put(2, "world!")
+
+	// assert
+	assert.Equal(t, int64(11), c.Cost())
+}
+
+func TestCache_WithMaxCost_ShouldRespectCapacity(t *testing.T) {
+	c, err := lru.NewCache("test", 2, 0, lru.WithMaxCost(100))
+	require.NoError(t, err)
+
+	// act
+	c.PutWithCost(1, 1, 1)
+	c.PutWithCost(2, 2, 1)
+	c.PutWithCost(3, 3, 1)
+
+	// assert
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(2), c.Cost())
+}
+
+func TestCache_PutWithSoftTTL_ShouldServeStaleUntilHardTTL(t *testing.T) {
+	c, err := lru.NewCache("test", 2, 0)
+	require.NoError(t, err)
+	c.PutWithSoftTTL(1, 1, 50*time.Millisecond, 100*time.Millisecond)
+
+	v, stale, ok := c.GetStale(1)
+	require.True(t, ok)
+	assert.False(t, stale)
+	assert.Equal(t, 1, v)
+
+	// act
+	time.Sleep(60 * time.Millisecond)
+
+	// assert
+	_, ok = c.Get(1)
+	assert.False(t, ok)
+	_, ok = c.Peek(1)
+	assert.False(t, ok)
+	v, stale, ok = c.GetStale(1)
+	require.True(t, ok)
+	assert.True(t, stale)
+	assert.Equal(t, 1, v)
+
+	time.Sleep(50 * time.Millisecond)
+	_, _, ok = c.GetStale(1)
+	assert.False(t, ok)
+}
+
+func TestCache_PutWithComputeTime_ShouldReturnExpirationAndComputeTime(t *testing.T) {
+	c, err := lru.NewCache("test", 2, 0)
+	require.NoError(t, err)
+	before := time.Now()
+
+	// act
+	c.PutWithComputeTime(1, 1, time.Minute, 10*time.Millisecond)
+
+	// assert
+	v, expires, computeTime, ok := c.GetWithComputeTime(1)
+	require.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 10*time.Millisecond, computeTime)
+	assert.WithinDuration(t, before.Add(time.Minute), expires, time.Second)
+}
+
+func TestCache_PutWithTTL_WithJitter_ShouldSpreadExpiration(t *testing.T) {
+	c, err := lru.NewCache("test", 2, 0, lru.WithTTLJitter(time.Minute), lru.WithRandSource(rand.NewSource(1)))
+	require.NoError(t, err)
+	before := time.Now()
+
+	// act
+	c.PutWithTTL(1, 1, time.Minute)
+	c.PutWithTTL(2, 2, time.Minute)
+
+	// assert
+	_, expires1, _, ok := c.GetWithComputeTime(1)
+	require.True(t, ok)
+	_, expires2, _, ok := c.GetWithComputeTime(2)
+	require.True(t, ok)
+	for _, expires := range []time.Time{expires1, expires2} {
+		assert.False(t, expires.Before(before.Add(time.Minute)))
+		assert.False(t, expires.After(time.Now().Add(2*time.Minute)))
+	}
+	assert.NotEqual(t, expires1, expires2)
+}
+
+func TestCache_WithSlidingTTL_GetShouldExtendExpiration(t *testing.T) {
+	c, err := lru.NewCache("test", 2, 100*time.Millisecond, lru.WithSlidingTTL())
+	require.NoError(t, err)
+	c.Put(1, 1)
+	c.PutWithFixedTTL(2, 2, 100*time.Millisecond)
+
+	// act
+	time.Sleep(60 * time.Millisecond)
+	_, ok1 := c.Get(1)
+	_, ok2 := c.Get(2)
+	time.Sleep(60 * time.Millisecond)
+
+	// assert
+	assert.True(t, ok1)
+	assert.True(t, ok2)
+	_, ok := c.Peek(1)
+	assert.True(t, ok)
+	_, ok = c.Peek(2)
+	assert.False(t, ok)
+
+	time.Sleep(60 * time.Millisecond)
+	_, ok = c.Get(1)
+	assert.False(t, ok)
+}
+
+func TestCache_PutWithSlidingTTL_ShouldOverrideFixedMode(t *testing.T) {
+	c, err := lru.NewCache("test", 2, 0)
+	require.NoError(t, err)
+	c.PutWithSlidingTTL(1, 1, 100*time.Millisecond)
+
+	// act
+	time.Sleep(60 * time.Millisecond)
+	_, ok := c.Get(1)
+	require.True(t, ok)
+	time.Sleep(60 * time.Millisecond)
+
+	// assert
+	_, ok = c.Get(1)
+	assert.True(t, ok)
+}
+
+func TestCache_WithClock_ShouldExpireByClock(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	c, err := lru.NewCache("test", 2, time.Minute, lru.WithClock(clock))
+	require.NoError(t, err)
+	c.Put(1, 1)
+
+	// act
+	clock.Advance(time.Minute - time.Nanosecond)
+	_, okBefore := c.Get(1)
+	clock.Advance(time.Nanosecond)
+	_, okAfter := c.Get(1)
+
+	// assert
+	assert.True(t, okBefore)
+	assert.False(t, okAfter)
+}
+
+func TestCache_GetWithMeta_ShouldReturnEntryMetadata(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	c, err := lru.NewCache("test", 2, time.Minute, lru.WithClock(clock))
+	require.NoError(t, err)
+	inserted := clock.Now()
+	c.Put(1, 1)
+	clock.Advance(time.Second)
+	c.Get(1)
+	clock.Advance(time.Second)
+
+	// act
+	meta, ok := c.GetWithMeta(1)
+	peeked, peekOK := c.PeekMeta(1)
+	_, missOK := c.GetWithMeta(2)
+
+	// assert
+	require.True(t, ok)
+	assert.Equal(t, 1, meta.Value)
+	assert.Equal(t, inserted, meta.Inserted)
+	assert.Equal(t, inserted.Add(time.Minute), meta.Expires)
+	assert.True(t, meta.Accessed.Equal(inserted.Add(2*time.Second)))
+	assert.Equal(t, uint64(2), meta.Hits)
+	assert.Equal(t, 58*time.Second, meta.Remaining(clock.Now()))
+	require.True(t, peekOK)
+	assert.Equal(t, meta.Hits, peeked.Hits)
+	assert.False(t, missOK)
+}
+
+func TestCache_BatchOperations_ShouldWorkLikeSingleOnes(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	c, err := lru.NewCache("test", 10, time.Minute, lru.WithClock(clock))
+	require.NoError(t, err)
+	c.PutMany([]cache.KeyValue{{Key: 1, Value: "a"}, {Key: 2, Value: "b"}, {Key: 3, Value: "c"}})
+	c.PutWithTTL(4, "d", time.Second)
+	clock.Advance(2 * time.Second)
+
+	// act
+	hits, missing := c.GetMany([]interface{}{1, 2, 4, 5})
+	c.RemoveMany([]interface{}{1, 2})
+
+	// assert
+	assert.Equal(t, map[interface{}]interface{}{1: "a", 2: "b"}, hits)
+	assert.Equal(t, []interface{}{4, 5}, missing)
+	assert.False(t, c.Contains(1))
+	assert.False(t, c.Contains(2))
+	assert.True(t, c.Contains(3))
+}
+
+func TestCache_RemoveByPrefix_ShouldRemoveMatchingKeys(t *testing.T) {
+	for _, opts := range [][]lru.Option{nil, {lru.WithKeyIndex()}} {
+		c, err := lru.NewCache("test", 10, 0, opts...)
+		require.NoError(t, err)
+		c.Put("user:42:orders", 1)
+		c.Put("user:42:cart", 2)
+		c.Put("user:43:cart", 3)
+		c.Put(42, 4)
+
+		// act
+		removed := c.RemoveByPrefix("user:42:")
+		matched := c.RemoveMatching(func(key interface{}) bool {
+			_, ok := key.(int)
+			return ok
+		})
+
+		// assert
+		assert.Equal(t, 2, removed)
+		assert.Equal(t, 1, matched)
+		assert.Equal(t, []interface{}{"user:43:cart"}, c.Keys())
+	}
+}
+
+func TestCache_WithKeyIndex_ShouldForgetEvictedKeys(t *testing.T) {
+	c, err := lru.NewCache("test", 1, 0, lru.WithKeyIndex())
+	require.NoError(t, err)
+	c.Put("a:1", 1)
+	c.Put("a:2", 2)
+
+	// act
+	removed := c.RemoveByPrefix("a:")
+
+	// assert
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCache_PutPinned_ShouldNotBeEvictedByCapacity(t *testing.T) {
+	c, err := lru.NewCache("test", 2, 0, lru.WithPinnedCap(1))
+	require.NoError(t, err)
+	require.NoError(t, c.PutPinned("config", 0))
+
+	// act
+	c.Put(1, 1)
+	c.Put(2, 2)
+	c.Put(3, 3)
+	c.Put("config", 42)
+	errFull := c.PutPinned("other", 0)
+
+	// assert
+	v, ok := c.Get("config")
+	require.True(t, ok)
+	assert.Equal(t, 42, v)
+	assert.Equal(t, 3, c.Len())
+	assert.Equal(t, 1, c.PinnedLen())
+	assert.ErrorIs(t, errFull, cache.ErrPinnedFull)
+	assert.False(t, c.Contains("other"))
+
+	// act
+	unpinned := c.Unpin("config")
+	c.Put(4, 4)
+	c.Put(5, 5)
+
+	// assert
+	assert.True(t, unpinned)
+	assert.False(t, c.Contains("config"))
+	assert.Equal(t, 0, c.PinnedLen())
+}
+
+func TestCache_PutPinned_ShouldExpireAndBeRemoved(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	c, err := lru.NewCache("test", 2, time.Minute, lru.WithPinnedCap(1), lru.WithClock(clock))
+	require.NoError(t, err)
+	require.NoError(t, c.PutPinned(1, 1))
+
+	// act
+	clock.Advance(time.Minute)
+	_, okExpired := c.Get(1)
+	errPin := c.PutPinned(2, 2)
+	c.Remove(2)
+
+	// assert
+	assert.False(t, okExpired)
+	assert.NoError(t, errPin)
+	assert.False(t, c.Contains(2))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCache_Update_ShouldNotLoseConcurrentUpdates(t *testing.T) {
+	c, err := lru.NewCache("test", 10, 0)
+	require.NoError(t, err)
+
+	// act
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Update("counter", func(old interface{}, exists bool) (interface{}, bool) {
+				if !exists {
+					return 1, true
+				}
+				return old.(int) + 1, true
+			})
+		}()
+	}
+	wg.Wait()
+
+	// assert
+	actual, loaded := c.PutIfAbsent("counter", 0)
+	assert.True(t, loaded)
+	assert.Equal(t, 50, actual)
+}
+
+func TestCache_Update_ShouldBeSerializedWithPutAndRemove(t *testing.T) {
+	cases := []struct {
+		name     string
+		write    func(c *lru.Cache)
+		expected interface{}
+		ok       bool
+	}{
+		{
+			name:     "put",
+			write:    func(c *lru.Cache) { c.Put("key", "put") },
+			expected: "put",
+			ok:       true,
+		},
+		{
+			name:  "remove",
+			write: func(c *lru.Cache) { c.Remove("key") },
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := lru.NewCache("test", 10, 0)
+			require.NoError(t, err)
+			c.Put("key", "old")
+
+			// act
+			written := make(chan struct{})
+			c.Update("key", func(interface{}, bool) (interface{}, bool) {
+				go func() {
+					defer close(written)
+					tc.write(c)
+				}()
+				// the write waits for the update instead of being overwritten by it
+				time.Sleep(10 * time.Millisecond)
+				return "updated", true
+			})
+			<-written
+
+			// assert
+			v, ok := c.Get("key")
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestCache_AtomicOperations_ShouldTreatExpiredAsAbsent(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	c, err := lru.NewCache("test", 10, time.Minute, lru.WithClock(clock))
+	require.NoError(t, err)
+
+	// act
+	_, loaded := c.PutIfAbsent(1, "a")
+	swapped := c.CompareAndSwap(1, "a", "b")
+	notSwapped := c.CompareAndSwap(1, "a", "c")
+	clock.Advance(time.Minute)
+	expiredSwapped := c.CompareAndSwap(1, "b", "d")
+	actual, loadedExpired := c.PutIfAbsent(1, "e")
+	_, kept := c.Update(1, func(interface{}, bool) (interface{}, bool) {
+		return nil, false
+	})
+
+	// assert
+	assert.False(t, loaded)
+	assert.True(t, swapped)
+	assert.False(t, notSwapped)
+	assert.False(t, expiredSwapped)
+	assert.False(t, loadedExpired)
+	assert.Equal(t, "e", actual)
+	assert.False(t, kept)
+	assert.False(t, c.Contains(1))
+}
+
+func TestCache_PutIfVersion_ShouldDetectReplacedEntries(t *testing.T) {
+	c, err := lru.NewCache("test", 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, c.PutIfVersion(1, "a", 0))
+	_, v1, ok := c.GetVersioned(1)
+	require.True(t, ok)
+
+	// act
+	c.Put(1, "b")
+	_, v2, _ := c.GetVersioned(1)
+	errStale := c.PutIfVersion(1, "c", v1)
+	errFresh := c.PutIfVersion(1, "d", v2)
+	errAbsent := c.PutIfVersion(2, "e", v2)
+
+	// assert
+	assert.Greater(t, v2, v1)
+	var mismatch *cache.VersionMismatchError
+	require.ErrorAs(t, errStale, &mismatch)
+	assert.Equal(t, v1, mismatch.Expected)
+	assert.Equal(t, v2, mismatch.Actual)
+	assert.NoError(t, errFresh)
+	assert.ErrorIs(t, errAbsent, cache.ErrVersionMismatch)
+	v, _ := c.Peek(1)
+	assert.Equal(t, "d", v)
+}
+
+func TestCache_PutIfVersion_ShouldSeeTheLastOfConcurrentPuts(t *testing.T) {
+	c, err := lru.NewCache("test", 10, 0)
+	require.NoError(t, err)
+
+	// act
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Put("key", i)
+		}(i)
+	}
+	wg.Wait()
+	_, version, ok := c.GetVersioned("key")
+	errStale := c.PutIfVersion("key", "stale", version-1)
+	errFresh := c.PutIfVersion("key", "fresh", version)
+
+	// assert
+	require.True(t, ok)
+	// the entry written last has the version of the last write
+	assert.Equal(t, uint64(50), version)
+	assert.ErrorIs(t, errStale, cache.ErrVersionMismatch)
+	assert.NoError(t, errFresh)
+}
@@ -0,0 +1,98 @@
+package lru
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+)
+
+type options struct {
+	maxCost       int64
+	estimate      cache.SizeEstimator
+	jitter        time.Duration
+	jitterPercent float64
+	randSource    rand.Source
+	clock         cache.Clock
+	keyIndex      bool
+	pinnedCap     int
+	sliding       bool
+}
+
+// Option configures the LRU cache.
+type Option func(*options)
+
+// WithMaxCost sets the cost budget of the cache (usually in bytes).
+// The least recently used items are evicted while the total cost of the items exceeds maxCost,
+// the capacity still limits the number of items.
+// Values put by Put and PutWithTTL are measured with the size estimator (see WithSizeEstimator),
+// PutWithCost allows to pass the cost explicitly.
+// By default (maxCost == 0) the cache is limited only by the capacity.
+func WithMaxCost(maxCost int64) Option {
+	return func(o *options) {
+		o.maxCost = maxCost
+	}
+}
+
+// WithSizeEstimator sets the function computing the cost of the values, cache.EstimateSize is used by default.
+func WithSizeEstimator(estimate cache.SizeEstimator) Option {
+	return func(o *options) {
+		o.estimate = estimate
+	}
+}
+
+// WithTTLJitter adds a random jitter from 0 to max to the TTL of every entry,
+// so the entries put at the same time do not expire at the same moment.
+func WithTTLJitter(max time.Duration) Option {
+	return func(o *options) {
+		o.jitter = max
+	}
+}
+
+// WithTTLJitterPercent adds a random jitter from 0 to percent of the TTL (e.g. 0.1 for 10%) to the TTL of every entry.
+// It can be combined with WithTTLJitter.
+func WithTTLJitterPercent(percent float64) Option {
+	return func(o *options) {
+		o.jitterPercent = percent
+	}
+}
+
+// WithRandSource sets the random source of the TTL jitter, e.g. a fixed-seed source for deterministic tests.
+func WithRandSource(src rand.Source) Option {
+	return func(o *options) {
+		o.randSource = src
+	}
+}
+
+// WithSlidingTTL makes the TTL of the entries sliding: an entry expires after its TTL of inactivity,
+// the reads (Get, GetStale, GetWithComputeTime) extend the expiration, Peek does not.
+// PutWithFixedTTL and PutWithSlidingTTL override the mode for an entry.
+func WithSlidingTTL() Option {
+	return func(o *options) {
+		o.sliding = true
+	}
+}
+
+// WithClock sets the clock of the cache used for the expiration, the metrics timing and the background loops,
+// e.g. a cache.FakeClock for deterministic tests. cache.SystemClock is used by default.
+func WithClock(clock cache.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithKeyIndex keeps the string keys in a radix tree, so RemoveByPrefix finds the keys without scanning all of them.
+// The index costs the memory for the keys and an update on every insertion and removal.
+func WithKeyIndex() Option {
+	return func(o *options) {
+		o.keyIndex = true
+	}
+}
+
+// WithPinnedCap limits the number of the pinned entries (see Cache.PutPinned), which are kept beyond the capacity.
+// By default (0) the limit is the capacity of the cache.
+func WithPinnedCap(n int) Option {
+	return func(o *options) {
+		o.pinnedCap = n
+	}
+}
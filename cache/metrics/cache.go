@@ -35,6 +35,31 @@ const (
 // 		"Counter of expired items in struct cache.",
 // 		[]string{labelSet},
 // 	)
+// 	staleCount = metrics.NewCounterVec(
+// 		"struct_cache_stale_total",
+// 		"Counter of stale items served from struct cache.",
+// 		[]string{labelSet},
+// 	)
+// 	refreshCount = metrics.NewCounterVec(
+// 		"struct_cache_refresh_total",
+// 		"Counter of background refreshes of items in struct cache.",
+// 		[]string{labelSet},
+// 	)
+// 	refreshErrorCount = metrics.NewCounterVec(
+// 		"struct_cache_refresh_error_total",
+// 		"Counter of failed background refreshes of items in struct cache.",
+// 		[]string{labelSet},
+// 	)
+// 	staleOnErrorCount = metrics.NewCounterVec(
+// 		"struct_cache_stale_on_error_total",
+// 		"Counter of stale items served from struct cache because the source failed.",
+// 		[]string{labelSet},
+// 	)
+// 	totalCost = metrics.NewGaugeVec(
+// 		"struct_cache_cost_total",
+// 		"Total cost (usually bytes) of items in struct cache.",
+// 		[]string{labelSet},
+// 	)
 // 	responseTime = metrics.NewHistogramVec(
 // 		"struct_cache_request_duration_seconds",
 // 		"Histogram of RT for the request to struct cache (seconds).",
@@ -49,6 +74,11 @@ const (
 // 		hitCount,
 // 		missCount,
 // 		expiredCount,
+// 		staleCount,
+// 		refreshCount,
+// 		refreshErrorCount,
+// 		staleOnErrorCount,
+// 		totalCost,
 // 		responseTime,
 // 	)
 // }
@@ -64,14 +94,24 @@ func NewCacheMetrics(name string) *CacheMetrics {
 		// HitCount:           hitCount.WithLabelValues(name),
 		// ExpiredCount:       expiredCount.WithLabelValues(name),
 		// MissCount:          missCount.WithLabelValues(name),
+		// StaleCount:         staleCount.WithLabelValues(name),
+		// RefreshCount:       refreshCount.WithLabelValues(name),
+		// RefreshErrorCount:  refreshErrorCount.WithLabelValues(name),
+		// StaleOnErrorCount:  staleOnErrorCount.WithLabelValues(name),
 		// ItemNumber:         itemNumber.WithLabelValues(name),
+		// TotalCost:          totalCost.WithLabelValues(name),
 		ResponseTimeSet:    ncm,
 		ResponseTimeGet:    ncm,
 		ResponseTimeDelete: ncm,
 		HitCount:           ncm,
 		ExpiredCount:       ncm,
 		MissCount:          ncm,
+		StaleCount:         ncm,
+		RefreshCount:       ncm,
+		RefreshErrorCount:  ncm,
+		StaleOnErrorCount:  ncm,
 		ItemNumber:         ncm,
+		TotalCost:          ncm,
 	}
 }
 
@@ -79,6 +119,8 @@ type noopCacheMetrics struct{}
 
 func (n noopCacheMetrics) Inc() { /* USE REAL IMPLEMENTATION FROM METRICS_GO */ }
 
+func (n noopCacheMetrics) Add(float64) { /* USE REAL IMPLEMENTATION FROM METRICS_GO */ }
+
 func (n noopCacheMetrics) Observe(float64) { /* USE REAL IMPLEMENTATION FROM METRICS_GO */ }
 
 func (n noopCacheMetrics) Set(float64) { /* USE REAL IMPLEMENTATION FROM METRICS_GO */ }
@@ -86,6 +128,8 @@ func (n noopCacheMetrics) Set(float64) { /* USE REAL IMPLEMENTATION FROM METRICS
 type counter interface {
 	// Inc increments the counter by 1.
 	Inc()
+	// Add adds the given value to the counter.
+	Add(float64)
 }
 
 type histogram interface {
@@ -104,14 +148,24 @@ type CacheMetrics struct {
 	ResponseTimeGet    histogram
 	ResponseTimeDelete histogram
 
-	HitCount     counter
-	ExpiredCount counter
-	MissCount    counter
+	HitCount          counter
+	ExpiredCount      counter
+	MissCount         counter
+	StaleCount        counter
+	RefreshCount      counter
+	RefreshErrorCount counter
+	StaleOnErrorCount counter
 
 	ItemNumber gauge
+	TotalCost  gauge
 }
 
 // SinceSeconds is a wrapper for time.Since(), converting the result to seconds.
 func SinceSeconds(started time.Time) float64 {
 	return float64(time.Since(started)) / float64(time.Second)
 }
+
+// Seconds converts the duration to seconds.
+func Seconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Second)
+}
@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache/metrics"
+)
+
+// NamespaceSeparator separates the namespace name from the string keys in the base cache,
+// so the names of the namespaces must not contain it.
+const NamespaceSeparator = ":"
+
+// ErrWrongNamespaceName is the namespace name error if it contains NamespaceSeparator.
+var ErrWrongNamespaceName = errors.New("wrong namespace name, it should not contain " + NamespaceSeparator)
+
+// NamespaceOption is an option of the NamespacedCache.
+type NamespaceOption func(*NamespacedCache)
+
+// WithNamespaceClock sets the clock of the metrics timing of the namespace, e.g. a FakeClock for deterministic tests.
+// SystemClock is used by default.
+func WithNamespaceClock(clock Clock) NamespaceOption {
+	return func(c *NamespacedCache) {
+		c.clock = clock
+	}
+}
+
+var (
+	_ NamedCache    = &NamespacedCache{}
+	_ KeysGetter    = &NamespacedCache{}
+	_ WithTTLPutter = &NamespacedCache{}
+	_ TTLGetter     = &NamespacedCache{}
+)
+
+// namespacedKey is the key of a namespace in the base cache for the keys which are not strings.
+type namespacedKey struct {
+	namespace string
+	key       interface{}
+}
+
+// NamespacedCache is a view of a namespace of a shared cache (see Namespace).
+type NamespacedCache struct {
+	base    Cache
+	name    string
+	prefix  string
+	metrics *metrics.CacheMetrics
+	clock   Clock
+}
+
+// Namespace returns the view of the base cache with its own keys, so many logical caches
+// (e.g. one per gRPC method) share the capacity of one cache without key collisions.
+// The string keys are stored in the base cache as name + NamespaceSeparator + key, the other keys are wrapped
+// (a ristretto base cache needs a Config.KeyToHash supporting them).
+// Keys, Len and Clear are scoped to the namespace, they scan the keys of the base cache, which must be a KeysGetter.
+// The namespace has its own metrics and can be registered in the Registry with its name.
+// The name must not contain NamespaceSeparator, otherwise the keys of the namespaces could collide.
+func Namespace(base Cache, name string, opts ...NamespaceOption) (*NamespacedCache, error) {
+	if strings.Contains(name, NamespaceSeparator) {
+		return nil, fmt.Errorf("can't create namespace %s: %w", name, ErrWrongNamespaceName)
+	}
+
+	c := &NamespacedCache{
+		base:    base,
+		name:    name,
+		prefix:  name + NamespaceSeparator,
+		metrics: metrics.NewCacheMetrics(name),
+		clock:   SystemClock,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Name returns the name of the namespace.
+func (c *NamespacedCache) Name() string {
+	return c.name
+}
+
+// Cap returns the capacity of the base cache, which is shared by its namespaces.
+func (c *NamespacedCache) Cap() int {
+	return c.base.Cap()
+}
+
+// Len returns the number of the keys of the namespace.
+func (c *NamespacedCache) Len() int {
+	return len(c.Keys())
+}
+
+// Keys returns the keys of the namespace, nil if the base cache is not a KeysGetter.
+func (c *NamespacedCache) Keys() []interface{} {
+	kg, ok := c.base.(KeysGetter)
+	if !ok {
+		return nil
+	}
+
+	var keys []interface{}
+	for _, key := range kg.Keys() {
+		if k, ok := c.unwrap(key); ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Clear removes the keys of the namespace from the base cache.
+func (c *NamespacedCache) Clear() {
+	if remover, ok := c.base.(PrefixRemover); ok {
+		remover.RemoveMatching(c.owns)
+		return
+	}
+
+	kg, ok := c.base.(KeysGetter)
+	if !ok {
+		return
+	}
+	for _, key := range FilterKeys(kg.Keys(), c.owns) {
+		c.base.Remove(key)
+	}
+}
+
+// Contains checks for the presence of the key in the namespace.
+func (c *NamespacedCache) Contains(key interface{}) bool {
+	return c.base.Contains(c.wrap(key))
+}
+
+// Get returns the value for the key of the namespace from the base cache.
+func (c *NamespacedCache) Get(key interface{}) (value interface{}, ok bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	return c.base.Get(c.wrap(key))
+}
+
+// Peek returns the value for the key of the namespace without any changes to the base cache.
+func (c *NamespacedCache) Peek(key interface{}) (value interface{}, ok bool) {
+	return c.base.Peek(c.wrap(key))
+}
+
+// Put stores the value in the base cache with the key of the namespace.
+func (c *NamespacedCache) Put(key, value interface{}) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.base.Put(c.wrap(key), value)
+}
+
+// PutWithTTL stores the value with the TTL if the base cache is a WithTTLPutter, otherwise it is Put.
+func (c *NamespacedCache) PutWithTTL(key, value interface{}, ttl time.Duration) {
+	putter, ok := c.base.(WithTTLPutter)
+	if !ok {
+		c.Put(key, value)
+		return
+	}
+
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	putter.PutWithTTL(c.wrap(key), value, ttl)
+}
+
+// TTL returns the default TTL of the base cache, 0 if it is not a TTLGetter.
+func (c *NamespacedCache) TTL() time.Duration {
+	if ttlGetter, ok := c.base.(TTLGetter); ok {
+		return ttlGetter.TTL()
+	}
+	return 0
+}
+
+// Remove removes the key of the namespace from the base cache.
+func (c *NamespacedCache) Remove(key interface{}) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.base.Remove(c.wrap(key))
+}
+
+// wrap returns the key of the base cache for the key of the namespace.
+func (c *NamespacedCache) wrap(key interface{}) interface{} {
+	if s, ok := key.(string); ok {
+		return c.prefix + s
+	}
+	return namespacedKey{namespace: c.name, key: key}
+}
+
+// unwrap returns the key of the namespace for the key of the base cache, false if it belongs to another namespace.
+func (c *NamespacedCache) unwrap(key interface{}) (interface{}, bool) {
+	switch k := key.(type) {
+	case string:
+		if strings.HasPrefix(k, c.prefix) {
+			return k[len(c.prefix):], true
+		}
+	case namespacedKey:
+		if k.namespace == c.name {
+			return k.key, true
+		}
+	}
+	return nil, false
+}
+
+func (c *NamespacedCache) owns(key interface{}) bool {
+	_, ok := c.unwrap(key)
+	return ok
+}
@@ -0,0 +1,107 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/lru"
+	"github.com/catalystgo/cache-go/cache/sieve"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	t.Parallel()
+
+	t.Run("isolate keys", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("shared", 10, 0)
+		require.NoError(t, err)
+		users, err := cache.Namespace(base, "users")
+		require.NoError(t, err)
+		orders, err := cache.Namespace(base, "orders")
+		require.NoError(t, err)
+
+		// act
+		users.Put("1", "user 1")
+		users.Put(2, "user 2")
+		orders.Put("1", "order 1")
+
+		// assert
+		v, ok := users.Get("1")
+		require.True(t, ok)
+		assert.Equal(t, "user 1", v)
+		v, ok = orders.Get("1")
+		require.True(t, ok)
+		assert.Equal(t, "order 1", v)
+		_, ok = orders.Get(2)
+		assert.False(t, ok)
+		assert.True(t, users.Contains(2))
+		assert.True(t, base.Contains("users:1"))
+		assert.ElementsMatch(t, []interface{}{"1", 2}, users.Keys())
+		assert.Equal(t, 2, users.Len())
+		assert.Equal(t, 1, orders.Len())
+		assert.Equal(t, 3, base.Len())
+		assert.Equal(t, base.Cap(), users.Cap())
+	})
+
+	t.Run("clear and remove scoped to namespace", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := sieve.NewCache("shared", 10, 0)
+		require.NoError(t, err)
+		users, err := cache.Namespace(base, "users")
+		require.NoError(t, err)
+		orders, err := cache.Namespace(base, "orders")
+		require.NoError(t, err)
+		users.Put("1", "user 1")
+		users.Put(2, "user 2")
+		orders.Put("1", "order 1")
+		orders.Put("2", "order 2")
+
+		// act
+		users.Clear()
+		orders.Remove("2")
+
+		// assert
+		assert.Equal(t, 0, users.Len())
+		assert.Equal(t, []interface{}{"1"}, orders.Keys())
+		assert.Equal(t, 1, base.Len())
+	})
+
+	t.Run("register in registry", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("shared", 10, 0)
+		require.NoError(t, err)
+		registry := cache.NewRegistry()
+		get, err := cache.Namespace(base, "/Service/Get")
+		require.NoError(t, err)
+		list, err := cache.Namespace(base, "/Service/List")
+		require.NoError(t, err)
+
+		// act
+		err = registry.Register(base, get, list)
+
+		// assert
+		require.NoError(t, err)
+		c, ok := registry.GetByName("/Service/Get")
+		require.True(t, ok)
+		c.Put("key", "value")
+		assert.True(t, base.Contains("/Service/Get:key"))
+	})
+
+	t.Run("wrong name", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("shared", 10, 0)
+		require.NoError(t, err)
+
+		// act
+		_, err = cache.Namespace(base, "users:admins")
+
+		// assert
+		require.ErrorIs(t, err, cache.ErrWrongNamespaceName)
+	})
+}
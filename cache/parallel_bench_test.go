@@ -11,6 +11,8 @@ import (
 	"github.com/catalystgo/cache-go/cache/arc"
 	"github.com/catalystgo/cache-go/cache/lru"
 	"github.com/catalystgo/cache-go/cache/ristretto"
+	"github.com/catalystgo/cache-go/cache/shardedlru"
+	"github.com/catalystgo/cache-go/cache/sieve"
 )
 
 func benchmarkParallelGet(b *testing.B, c cache.Cache, workers, iterations int) {
@@ -79,6 +81,18 @@ func BenchmarkParallelGet(b *testing.B) {
 		return c
 	}
 
+	shardedLRUFactory := func() cache.NamedCache {
+		c, _ := shardedlru.NewCache("data", capValue, time.Minute)
+
+		return c
+	}
+
+	sieveFactory := func() cache.NamedCache {
+		c, _ := sieve.NewCache("data", capValue, time.Minute)
+
+		return c
+	}
+
 	cases := []struct {
 		c          cache.NamedCache
 		workers    int
@@ -101,6 +115,18 @@ func BenchmarkParallelGet(b *testing.B) {
 		{c: ristrettoFactory(), workers: 8, iterations: 100_000},
 		{c: ristrettoFactory(), workers: 16, iterations: 100_000},
 		{c: ristrettoFactory(), workers: 32, iterations: 100_000},
+
+		{c: shardedLRUFactory(), workers: 1, iterations: 100_000},
+		{c: shardedLRUFactory(), workers: 4, iterations: 100_000},
+		{c: shardedLRUFactory(), workers: 8, iterations: 100_000},
+		{c: shardedLRUFactory(), workers: 16, iterations: 100_000},
+		{c: shardedLRUFactory(), workers: 32, iterations: 100_000},
+
+		{c: sieveFactory(), workers: 1, iterations: 100_000},
+		{c: sieveFactory(), workers: 4, iterations: 100_000},
+		{c: sieveFactory(), workers: 8, iterations: 100_000},
+		{c: sieveFactory(), workers: 16, iterations: 100_000},
+		{c: sieveFactory(), workers: 32, iterations: 100_000},
 	}
 
 	for _, tc := range cases {
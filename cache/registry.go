@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/catalystgo/tracerok/logger"
@@ -17,6 +18,15 @@ type Registry interface {
 	MustRegister(cache NamedCache, err error)
 }
 
+// RegistryLister is an interface for the registries listing their caches, e.g. for the admin API.
+// The registry of NewRegistry implements it.
+type RegistryLister interface {
+	// List returns all registered cache instances sorted by name.
+	List() []NamedCache
+}
+
+var _ RegistryLister = &cacheRegistry{}
+
 type registryOpt func(*cacheRegistry)
 
 func WithLoggerErrorf(f func(ctx context.Context, format string, args ...interface{})) registryOpt {
@@ -108,3 +118,17 @@ func (r *cacheRegistry) GetByName(name string) (NamedCache, bool) {
 	s, ok := r.caches[name]
 	return s, ok
 }
+
+// List returns all registered cache instances sorted by name.
+func (r *cacheRegistry) List() []NamedCache {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	caches := make([]NamedCache, 0, len(r.caches))
+	for _, c := range r.caches {
+		caches = append(caches, c)
+	}
+	sort.Slice(caches, func(i, j int) bool {
+		return caches[i].Name() < caches[j].Name()
+	})
+	return caches
+}
@@ -213,4 +213,24 @@ func TestRegistry(t *testing.T) {
 		_, ok := r.GetByName("cache-1")
 		require.True(t, ok)
 	})
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+
+		cache1 := mock.NewMockNamedCache(ctrl)
+		cache1.EXPECT().Name().Return("cache-b").AnyTimes()
+
+		cache2 := mock.NewMockNamedCache(ctrl)
+		cache2.EXPECT().Name().Return("cache-a").AnyTimes()
+
+		r := cache.NewRegistry()
+		require.NoError(t, r.Register(cache1, cache2))
+
+		// act
+		caches := r.(cache.RegistryLister).List()
+
+		// assert
+		require.Equal(t, []cache.NamedCache{cache2, cache1}, caches)
+	})
 }
@@ -3,11 +3,15 @@ package ristretto
 import (
 	"fmt"
 	"io"
+	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/catalystgo/cache-go/cache"
 	"github.com/catalystgo/cache-go/cache/metrics"
 	"github.com/dgraph-io/ristretto"
+	"github.com/dgraph-io/ristretto/z"
 )
 
 const (
@@ -15,28 +19,110 @@ const (
 )
 
 var (
-	_ cache.NamedCache    = &Cache{}
-	_ cache.WithTTLPutter = &Cache{}
-	_ io.Closer           = &Cache{}
-	_ cache.CapSetter     = &Cache{}
+	_ cache.NamedCache        = &Cache{}
+	_ cache.WithTTLPutter     = &Cache{}
+	_ io.Closer               = &Cache{}
+	_ cache.CapSetter         = &Cache{}
+	_ cache.CostPutter        = &Cache{}
+	_ cache.KeysGetter        = &Cache{}
+	_ cache.SoftTTLPutter     = &Cache{}
+	_ cache.StaleGetter       = &Cache{}
+	_ cache.TTLGetter         = &Cache{}
+	_ cache.ComputeTimePutter = &Cache{}
+	_ cache.ComputeTimeGetter = &Cache{}
+	_ cache.BatchGetter       = &Cache{}
+	_ cache.BatchPutter       = &Cache{}
+	_ cache.PrefixRemover     = &Cache{}
+	_ cache.MetaGetter        = &Cache{}
+	_ cache.AtomicUpdater     = &Cache{}
+	_ cache.Versioner         = &Cache{}
 )
 
 // Cache is a wrapper around ristretto.Cache.
+//
+// Ristretto knows only the hashes of the keys, so the wrapper keeps its own index of the stored keys
+// (kept in sync via OnEvict, OnReject and Remove) to report the accurate Len and Keys.
 type Cache struct {
-	cache   *ristretto.Cache
+	cache     *ristretto.Cache
+	keyToHash func(key interface{}) (uint64, uint64)
+
+	mu    sync.Mutex
+	index map[uint64]*entry
+	// keys is the index of the string keys, nil if it is disabled (see Config.KeyIndex), it is updated with index
+	keys *cache.KeyIndex
+
 	close   chan struct{}
 	metrics *metrics.CacheMetrics
 	name    string
 	cap     int
 	ttl     time.Duration
 	cost    int64
+	costFn  cache.SizeEstimator
+	jitter  *cache.Jitter
+	clock   cache.Clock
+	// version is the version of the last indexed entry, it is incremented under mu (see PutIfVersion)
+	version atomic.Uint64
+}
+
+// entry is the value stored in ristretto, it keeps the original key for the index.
+type entry struct {
+	key     interface{}
+	value   interface{}
+	expires time.Time
+	stale   time.Time
+	// computeTime is the time it took to compute the value (see PutWithComputeTime)
+	computeTime time.Duration
+	// version is the version of the write (see Cache.PutIfVersion)
+	version uint64
+	// inserted is the time the entry was put
+	inserted time.Time
+	// accessed is the time of the last read (or the put) in unix nanoseconds
+	accessed atomic.Int64
+	// hits is the number of the reads of the entry
+	hits atomic.Uint64
+}
+
+// isStale reports whether the soft TTL of the entry has passed.
+func (e *entry) isStale(now time.Time) bool {
+	return !e.stale.IsZero() && !now.Before(e.stale)
+}
+
+// touch records the read of the entry.
+func (e *entry) touch(now time.Time) {
+	e.accessed.Store(now.UnixNano())
+	e.hits.Add(1)
+}
+
+// meta returns the metadata of the entry.
+func (e *entry) meta() cache.EntryMeta {
+	return cache.EntryMeta{
+		Value:    e.value,
+		Inserted: e.inserted,
+		Expires:  e.expires,
+		Accessed: time.Unix(0, e.accessed.Load()),
+		Hits:     e.hits.Load(),
+	}
 }
 
 // Config is a wrapper around ristretto.Config.
 type Config struct {
 	ristretto.Config
-	TTL  time.Duration // Default TTL for cache keys
-	Cost int64         // Cost parameter for calls to Set (default 1)
+	TTL      time.Duration       // Default TTL for cache keys
+	Cost     int64               // Cost parameter for calls to Set (default 1)
+	CostFunc cache.SizeEstimator // Cost of the values put by Put and PutWithTTL, overrides Cost if set (e.g. cache.EstimateSize)
+
+	TTLJitter        time.Duration // Random jitter from 0 to TTLJitter added to the TTL of every entry
+	TTLJitterPercent float64       // Random jitter from 0 to TTLJitterPercent of the TTL (e.g. 0.1 for 10%) added to the TTL of every entry
+	RandSource       rand.Source   // Random source of the TTL jitter, e.g. a fixed-seed source for deterministic tests
+
+	// KeyIndex keeps the string keys in a radix tree, so RemoveByPrefix finds the keys without scanning all of them.
+	// The index costs the memory for the keys and an update on every insertion and removal.
+	KeyIndex bool
+
+	// Clock of the expiration, the metrics timing and the background loops, e.g. a cache.FakeClock for deterministic tests
+	// (cache.SystemClock by default). Ristretto removes the expired entries by the system time, the cache treats the entries
+	// expired by the Clock as missing until then.
+	Clock cache.Clock
 }
 
 // BuildConfig creates a configuration based on https://github.com/dgraph-io/ristretto#config recommendations.
@@ -55,6 +141,17 @@ func BuildConfig(capacity int, ttl time.Duration) Config {
 	}
 }
 
+// BuildSizeConfig creates a configuration for a cache limited by the total size of the values.
+// maxCost is the cache budget in bytes, items is the expected number of items in the cache (used to size the counters),
+// ttl is the default cache key lifetime. The cost of the values is estimated with cache.EstimateSize.
+func BuildSizeConfig(maxCost int64, items int, ttl time.Duration) Config {
+	config := BuildConfig(items, ttl)
+	config.Config.MaxCost = maxCost
+	config.CostFunc = cache.EstimateSize
+
+	return config
+}
+
 // New returns a new Cache instance.
 // name is the cache name, capacity is the cache capacity, ttl is the default cache key lifetime.
 func New(name string, capacity int, ttl time.Duration) (*Cache, error) {
@@ -66,27 +163,61 @@ func New(name string, capacity int, ttl time.Duration) (*Cache, error) {
 //
 //	config := ristretto.BuildConfig(1000, time.Minute)
 //
-//	config.Config.OnEvict = func(key, conflict uint64, value interface{}, cost int64) {
-//	  // custom evict callback
+//	config.Config.OnEvict = func(item *ristretto.Item) {
+//	  // custom evict callback, item.Value is the value passed to Put
 //	}
 //
 //	c, err := ristretto.NewWithConfig("namespace", config)
 func NewWithConfig(name string, config Config) (*Cache, error) {
-	r, err := ristretto.NewCache(&config.Config)
+	jitter, err := cache.NewJitter(config.TTLJitter, config.TTLJitterPercent, config.RandSource)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create ristretto: %w", err)
+		return nil, fmt.Errorf("can't create cache %s: %w", name, err)
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = cache.SystemClock
 	}
 
 	c := &Cache{
-		cache:   r,
-		close:   make(chan struct{}),
-		metrics: metrics.NewCacheMetrics(name),
-		name:    name,
-		cap:     int(config.Config.MaxCost),
-		ttl:     config.TTL,
-		cost:    config.Cost,
+		keyToHash: config.Config.KeyToHash,
+		index:     make(map[uint64]*entry),
+		close:     make(chan struct{}),
+		metrics:   metrics.NewCacheMetrics(name),
+		name:      name,
+		cap:       int(config.Config.MaxCost),
+		ttl:       config.TTL,
+		cost:      config.Cost,
+		costFn:    config.CostFunc,
+		jitter:    jitter,
+		clock:     clock,
+	}
+	if c.keyToHash == nil {
+		c.keyToHash = z.KeyToHash
+	}
+	if config.KeyIndex {
+		c.keys = cache.NewKeyIndex()
+	}
+
+	rc := config.Config
+	rc.OnEvict = c.onRemoved(config.Config.OnEvict, false)
+	rc.OnReject = c.onRemoved(config.Config.OnReject, true)
+	if onExit := config.Config.OnExit; onExit != nil {
+		rc.OnExit = func(val interface{}) {
+			onExit(unwrap(val))
+		}
+	}
+	if costFn := config.Config.Cost; costFn != nil {
+		rc.Cost = func(val interface{}) int64 {
+			return costFn(unwrap(val))
+		}
 	}
 
+	r, err := ristretto.NewCache(&rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ristretto: %w", err)
+	}
+	c.cache = r
+
 	go c.stats()
 
 	return c, nil
@@ -97,19 +228,51 @@ func (c *Cache) Cap() int {
 	return c.cap
 }
 
-// Len returns the size of the cache.
+// TTL returns the default TTL of the cache, 0 means no TTL.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Len returns the number of items in the cache.
+// Expired items are counted until ristretto cleans them up.
 func (c *Cache) Len() int {
-	return int(c.cache.Metrics.KeysAdded() - c.cache.Metrics.CostEvicted())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.index)
+}
+
+// Keys returns a list of saved keys in no particular order.
+func (c *Cache) Keys() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]interface{}, 0, len(c.index))
+	for _, e := range c.index {
+		keys = append(keys, e.key)
+	}
+	return keys
 }
 
 // Clear completely clears the cache.
 func (c *Cache) Clear() {
+	// ristretto calls OnEvict synchronously here, so the lock can't be held
 	c.cache.Clear()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys != nil {
+		for _, e := range c.index {
+			c.keys.Delete(e.key)
+		}
+	}
+	c.index = make(map[uint64]*entry)
 }
 
 // Contains checks for the existence of a key in the cache.
 func (c *Cache) Contains(key interface{}) bool {
-	_, ok := c.cache.Get(key)
+	_, ok := c.Peek(key)
 
 	return ok
 }
@@ -118,11 +281,63 @@ func (c *Cache) Contains(key interface{}) bool {
 // and returns a boolean indicating whether the value was found.
 // If the TTL of the key has expired, it returns nil and false.
 func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
-	start := time.Now()
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	e, _, expired := c.get(key, start, false)
+	if e == nil {
+		return nil, false
+	}
+	e.touch(start)
+	return e.value, true
+}
+
+// GetStale retrieves a value by key like Get, but also returns the entries which soft TTL has passed
+// (see PutWithSoftTTL), stale reports whether the soft TTL of the entry has passed.
+func (c *Cache) GetStale(key interface{}) (value interface{}, stale bool, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok && stale {
+			c.metrics.StaleCount.Inc()
+		} else if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	e, stale, expired := c.get(key, start, true)
+	if e == nil {
+		return nil, false, false
+	}
+	e.touch(start)
+	return e.value, stale, true
+}
+
+// GetWithComputeTime retrieves a value by key like Get, with its expiration time and the time it took to compute it
+// (see PutWithComputeTime).
+func (c *Cache) GetWithComputeTime(key interface{}) (value interface{}, expires time.Time, computeTime time.Duration, ok bool) {
+	start := c.clock.Now()
 	expired := false
 
 	defer func() {
-		c.metrics.ResponseTimeGet.Observe(metrics.SinceSeconds(start))
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
 		if ok {
 			c.metrics.HitCount.Inc()
 		} else if expired {
@@ -132,12 +347,74 @@ func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
 		}
 	}()
 
-	return c.cache.Get(key)
+	e, _, expired := c.get(key, start, false)
+	if e == nil {
+		return nil, time.Time{}, 0, false
+	}
+	e.touch(start)
+	return e.value, e.expires, e.computeTime, true
+}
+
+// GetWithMeta retrieves a value by key like Get, with the metadata of the entry: the insertion, the expiration
+// and the last access time and the number of the reads, including this one.
+func (c *Cache) GetWithMeta(key interface{}) (meta cache.EntryMeta, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	e, _, expired := c.get(key, start, false)
+	if e == nil {
+		return cache.EntryMeta{}, false
+	}
+	e.touch(start)
+	return e.meta(), true
+}
+
+// PeekMeta returns the metadata of the entry like GetWithMeta, without updating the access time and the number of the reads.
+func (c *Cache) PeekMeta(key interface{}) (meta cache.EntryMeta, ok bool) {
+	e, _, _ := c.get(key, c.clock.Now(), false)
+	if e == nil {
+		return cache.EntryMeta{}, false
+	}
+	return e.meta(), true
 }
 
 // Peek is the same as Get, but does not report metrics.
 func (c *Cache) Peek(key interface{}) (value interface{}, ok bool) {
-	return c.cache.Get(key)
+	e, _, _ := c.get(key, c.clock.Now(), false)
+	if e == nil {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// get returns the entry of the key or nil, the stale entries are returned only if withStale is set.
+// ristretto drops the entries after the hard TTL by itself.
+func (c *Cache) get(key interface{}, now time.Time, withStale bool) (e *entry, stale, expired bool) {
+	v, ok := c.cache.Get(key)
+	if !ok {
+		return nil, false, false
+	}
+	e = v.(*entry)
+	// ristretto expires the entries by the system time, the clock of the cache may be ahead of it (see Config.Clock)
+	if !e.expires.IsZero() && !now.Before(e.expires) {
+		return nil, false, true
+	}
+	stale = e.isStale(now)
+	if stale && !withStale {
+		return nil, false, true
+	}
+	return e, stale, false
 }
 
 // Put puts a key-value pair into the cache.
@@ -148,32 +425,388 @@ func (c *Cache) Put(key interface{}, value interface{}) {
 
 // PutWithTTL adds a key-value pair to the cache with a specified TTL.
 // If ttl <= 0, no TTL is added.
+// The cost of the value is computed by Config.CostFunc if it is set, Config.Cost is used otherwise.
 func (c *Cache) PutWithTTL(key, value interface{}, ttl time.Duration) {
-	start := time.Now()
+	cost := c.cost
+	if c.costFn != nil {
+		cost = c.costFn(value)
+	}
+	c.put(key, value, 0, ttl, 0, cost)
+}
+
+// PutWithCost adds a key-value pair with the specified cost to the cache.
+// It uses the default TTL specified in New.
+func (c *Cache) PutWithCost(key, value interface{}, cost int64) {
+	c.put(key, value, 0, c.ttl, 0, cost)
+}
+
+// PutWithComputeTime adds a key-value pair to the cache with a specified TTL and the time it took to compute the value,
+// which is used by the probabilistic early expiration (see cache.WithXFetch).
+func (c *Cache) PutWithComputeTime(key, value interface{}, ttl, computeTime time.Duration) {
+	cost := c.cost
+	if c.costFn != nil {
+		cost = c.costFn(value)
+	}
+	c.put(key, value, 0, ttl, computeTime, cost)
+}
+
+// PutWithSoftTTL adds a key-value pair to the cache with the soft and hard TTLs.
+// After softTTL the entry is stale: Get and Peek treat it as expired, GetStale returns it until hardTTL.
+// If softTTL <= 0, the entry never becomes stale. If hardTTL <= 0, no hard TTL is added.
+func (c *Cache) PutWithSoftTTL(key, value interface{}, softTTL, hardTTL time.Duration) {
+	cost := c.cost
+	if c.costFn != nil {
+		cost = c.costFn(value)
+	}
+	c.put(key, value, softTTL, hardTTL, 0, cost)
+}
+
+func (c *Cache) put(key, value interface{}, softTTL, ttl, computeTime time.Duration, cost int64) {
+	start := c.clock.Now()
 
 	defer func() {
-		c.metrics.ResponseTimeSet.Observe(metrics.SinceSeconds(start))
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
 	}()
 
-	if ttl == 0 {
-		c.cache.Set(key, value, c.cost)
+	c.set(key, value, start, softTTL, ttl, computeTime, cost)
+}
+
+// set stores the entry put at start in ristretto and in the index.
+func (c *Cache) set(key, value interface{}, start time.Time, softTTL, ttl, computeTime time.Duration, cost int64) {
+	e, ttl := c.newEntry(key, value, start, softTTL, ttl, computeTime)
+	hash, _ := c.keyToHash(key)
+
+	// the key is indexed before Set, so a concurrent rejection of the new entry finds it in the index
+	c.mu.Lock()
+	prev, existed := c.index[hash]
+	// the version is given under the lock, so the versions of the indexed entries of a key only grow
+	e.version = c.version.Add(1)
+	c.indexLocked(hash, e)
+	c.mu.Unlock()
+
+	c.store(hash, e, prev, existed, cost, ttl)
+}
+
+// newEntry creates the entry put at start, it returns the entry and its TTL with the jitter.
+func (c *Cache) newEntry(key, value interface{}, start time.Time, softTTL, ttl, computeTime time.Duration) (*entry, time.Duration) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	// the same jitter is added to the soft TTL to keep the stale period
+	jitter := c.jitter.Duration(ttl)
+	ttl += jitter
+	if softTTL > 0 {
+		softTTL += jitter
+	}
+
+	e := &entry{key: key, value: value, computeTime: computeTime, inserted: start}
+	e.accessed.Store(start.UnixNano())
+	if ttl > 0 {
+		e.expires = start.Add(ttl)
+	}
+	if softTTL > 0 && (ttl == 0 || softTTL < ttl) {
+		e.stale = start.Add(softTTL)
+	}
+	return e, ttl
+}
+
+// store sets the entry indexed in place of prev in ristretto, the index is restored if the set is dropped.
+func (c *Cache) store(hash uint64, e, prev *entry, existed bool, cost int64, ttl time.Duration) {
+	if c.cache.SetWithTTL(e.key, e, cost, ttl) {
 		return
 	}
 
-	c.cache.SetWithTTL(key, value, c.cost, ttl)
+	// the set was dropped, the previous entry (if any) is still in the cache
+	c.mu.Lock()
+	if c.index[hash] == e {
+		if existed {
+			c.indexLocked(hash, prev)
+		} else {
+			c.unindexLocked(hash)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// indexLocked indexes the entry by the hash of its key, c.mu must be held.
+func (c *Cache) indexLocked(hash uint64, e *entry) {
+	if prev, ok := c.index[hash]; ok && prev.key != e.key {
+		c.keys.Delete(prev.key)
+	}
+	c.index[hash] = e
+	c.keys.Insert(e.key)
+}
+
+// unindexLocked removes the entry of the hash from the index, c.mu must be held.
+func (c *Cache) unindexLocked(hash uint64) {
+	if e, ok := c.index[hash]; ok {
+		c.keys.Delete(e.key)
+		delete(c.index, hash)
+	}
 }
 
 // Remove removes a value by key from the cache.
 func (c *Cache) Remove(key interface{}) {
-	start := time.Now()
+	start := c.clock.Now()
 
 	defer func() {
-		c.metrics.ResponseTimeDelete.Observe(metrics.SinceSeconds(start))
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
 	}()
 
+	c.remove(key)
+}
+
+func (c *Cache) remove(key interface{}) {
+	hash, _ := c.keyToHash(key)
+
+	c.mu.Lock()
+	c.unindexLocked(hash)
+	c.mu.Unlock()
+
 	c.cache.Del(key)
 }
 
+// GetMany retrieves the values by keys like Get, it returns the found values by key
+// and the keys which are missing or expired, in the order of keys. The metrics are recorded once per batch.
+func (c *Cache) GetMany(keys []interface{}) (hits map[interface{}]interface{}, missing []interface{}) {
+	start := c.clock.Now()
+	hit, expired := 0, 0
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		c.metrics.HitCount.Add(float64(hit))
+		c.metrics.ExpiredCount.Add(float64(expired))
+		c.metrics.MissCount.Add(float64(len(missing) - expired))
+	}()
+
+	hits = make(map[interface{}]interface{}, len(keys))
+	for _, key := range keys {
+		e, _, isExpired := c.get(key, start, false)
+		if e != nil {
+			e.touch(start)
+			hits[key] = e.value
+			hit++
+			continue
+		}
+		if isExpired {
+			expired++
+		}
+		missing = append(missing, key)
+	}
+	return hits, missing
+}
+
+// PutMany adds the key-value pairs to the cache with the default TTL, in the order of items.
+// The cost of the values is computed like in PutWithTTL. The metrics are recorded once per batch.
+func (c *Cache) PutMany(items []cache.KeyValue) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	for _, item := range items {
+		cost := c.cost
+		if c.costFn != nil {
+			cost = c.costFn(item.Value)
+		}
+		c.set(item.Key, item.Value, start, 0, c.ttl, 0, cost)
+	}
+}
+
+// RemoveMany removes the values by keys from the cache, the metrics are recorded once per batch.
+func (c *Cache) RemoveMany(keys []interface{}) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	for _, key := range keys {
+		c.remove(key)
+	}
+}
+
+// RemoveByPrefix removes the entries which keys are strings with the prefix, it returns the number of removed entries.
+// With Config.KeyIndex the keys are found in the index, otherwise all keys are scanned.
+func (c *Cache) RemoveByPrefix(prefix string) int {
+	var keys []interface{}
+	if c.keys != nil {
+		keys = c.keys.KeysWithPrefix(prefix)
+	} else {
+		keys = cache.FilterKeys(c.Keys(), cache.HasPrefix(prefix))
+	}
+	c.RemoveMany(keys)
+	return len(keys)
+}
+
+// RemoveMatching removes the entries which keys match the predicate, it returns the number of removed entries.
+// All keys are scanned.
+func (c *Cache) RemoveMatching(match func(key interface{}) bool) int {
+	keys := cache.FilterKeys(c.Keys(), match)
+	c.RemoveMany(keys)
+	return len(keys)
+}
+
+// PutIfAbsent puts the value with the default TTL if the key is absent, expired or stale,
+// it returns the current value of the key and whether it was loaded.
+// The atomic operations (PutIfAbsent, CompareAndSwap and Update) check the index of the keys, which is updated
+// by all the writes under the same lock. Like Put, the written value may still be dropped by ristretto.
+func (c *Cache) PutIfAbsent(key, value interface{}) (actual interface{}, loaded bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	e, ttl := c.newEntry(key, value, start, 0, c.ttl, 0)
+	hash, _ := c.keyToHash(key)
+
+	c.mu.Lock()
+	prev, existed := c.index[hash]
+	if old, ok := currentOf(prev, key, start); ok {
+		c.mu.Unlock()
+		return old, true
+	}
+	e.version = c.version.Add(1)
+	c.indexLocked(hash, e)
+	c.mu.Unlock()
+
+	c.store(hash, e, prev, existed, c.costOf(value), ttl)
+	return value, false
+}
+
+// CompareAndSwap replaces the value of the key with newValue and the default TTL if the current value equals oldValue,
+// it reports whether the value was swapped. The absent, expired and stale keys are not swapped, the values must be comparable.
+func (c *Cache) CompareAndSwap(key, oldValue, newValue interface{}) bool {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	e, ttl := c.newEntry(key, newValue, start, 0, c.ttl, 0)
+	hash, _ := c.keyToHash(key)
+
+	c.mu.Lock()
+	prev, existed := c.index[hash]
+	if old, ok := currentOf(prev, key, start); !ok || old != oldValue {
+		c.mu.Unlock()
+		return false
+	}
+	e.version = c.version.Add(1)
+	c.indexLocked(hash, e)
+	c.mu.Unlock()
+
+	c.store(hash, e, prev, existed, c.costOf(newValue), ttl)
+	return true
+}
+
+// Update calls fn with the current value of the key (exists is false if it is absent, expired or stale)
+// and puts the returned value with the default TTL if keep is true, otherwise it removes the key.
+// It returns the new value and keep. fn is called under the index lock, so it must not call the cache.
+func (c *Cache) Update(
+	key interface{},
+	fn func(old interface{}, exists bool) (value interface{}, keep bool),
+) (value interface{}, ok bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	hash, _ := c.keyToHash(key)
+
+	c.mu.Lock()
+	prev, existed := c.index[hash]
+	old, exists := currentOf(prev, key, start)
+	value, ok = fn(old, exists)
+	if !ok {
+		c.unindexLocked(hash)
+		c.mu.Unlock()
+		c.cache.Del(key)
+		return nil, false
+	}
+	e, ttl := c.newEntry(key, value, start, 0, c.ttl, 0)
+	e.version = c.version.Add(1)
+	c.indexLocked(hash, e)
+	c.mu.Unlock()
+
+	c.store(hash, e, prev, existed, c.costOf(value), ttl)
+	return value, true
+}
+
+// GetVersioned retrieves a value by key like Get, with the version of the entry (see PutIfVersion).
+// A value which is not yet stored by ristretto is reported with the version of the previous one.
+func (c *Cache) GetVersioned(key interface{}) (value interface{}, version uint64, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	e, _, expired := c.get(key, start, false)
+	if e == nil {
+		return nil, 0, false
+	}
+	e.touch(start)
+	return e.value, e.version, true
+}
+
+// PutIfVersion puts the value with the default TTL if the version of the key in the index is expectedVersion,
+// 0 means the key is absent, expired or stale. Otherwise it returns *cache.VersionMismatchError.
+func (c *Cache) PutIfVersion(key, value interface{}, expectedVersion uint64) error {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	e, ttl := c.newEntry(key, value, start, 0, c.ttl, 0)
+	hash, _ := c.keyToHash(key)
+
+	c.mu.Lock()
+	prev, existed := c.index[hash]
+	var version uint64
+	if _, ok := currentOf(prev, key, start); ok {
+		version = prev.version
+	}
+	if version != expectedVersion {
+		c.mu.Unlock()
+		return &cache.VersionMismatchError{Key: key, Expected: expectedVersion, Actual: version}
+	}
+	e.version = c.version.Add(1)
+	c.indexLocked(hash, e)
+	c.mu.Unlock()
+
+	c.store(hash, e, prev, existed, c.costOf(value), ttl)
+	return nil
+}
+
+// currentOf returns the value of the indexed entry e of the key if it is fresh at now.
+func currentOf(e *entry, key interface{}, now time.Time) (interface{}, bool) {
+	if e == nil || e.key != key || (!e.expires.IsZero() && !now.Before(e.expires)) || e.isStale(now) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// costOf returns the cost of the value put without an explicit cost.
+func (c *Cache) costOf(value interface{}) int64 {
+	if c.costFn != nil {
+		return c.costFn(value)
+	}
+	return c.cost
+}
+
 // Name returns the cache name.
 func (c *Cache) Name() string {
 	return c.name
@@ -201,12 +834,55 @@ func (c *Cache) Close() error {
 func (c *Cache) stats() {
 	for {
 		select {
-		case <-time.After(calcItemNumberInterval):
+		case <-c.clock.After(calcItemNumberInterval):
 		case <-c.close:
 			return
 		}
 		c.metrics.ItemNumber.Set(float64(c.Len()))
+		c.metrics.TotalCost.Set(float64(c.Cost()))
+	}
+}
+
+// Cost returns the total cost of the items in the cache.
+// It requires Config.Metrics to be enabled (see BuildConfig), otherwise it returns 0.
+func (c *Cache) Cost() int64 {
+	return int64(c.cache.Metrics.CostAdded() - c.cache.Metrics.CostEvicted())
+}
+
+// onRemoved keeps the index in sync with the items evicted or rejected by ristretto,
+// and calls the original callback with the value passed to Put.
+func (c *Cache) onRemoved(orig func(item *ristretto.Item), rejected bool) func(item *ristretto.Item) {
+	return func(item *ristretto.Item) {
+		if e, ok := item.Value.(*entry); ok {
+			c.mu.Lock()
+			// the callback may come for an entry that is already replaced by a newer Put
+			if c.index[item.Key] == e && !(rejected && c.stored(e.key)) {
+				c.unindexLocked(item.Key)
+			}
+			c.mu.Unlock()
+		}
+
+		if orig != nil {
+			unwrapped := *item
+			unwrapped.Value = unwrap(item.Value)
+			orig(&unwrapped)
+		}
+	}
+}
+
+// stored checks if ristretto keeps a value for the key.
+// ristretto rejects a repeated Set of a new key while keeping the first value, so a rejection does not always mean removal.
+// It must not be called from OnEvict, which ristretto calls under the store lock.
+func (c *Cache) stored(key interface{}) bool {
+	_, ok := c.cache.GetTTL(key)
+	return ok
+}
+
+func unwrap(v interface{}) interface{} {
+	if e, ok := v.(*entry); ok {
+		return e.value
 	}
+	return v
 }
 
 // SetCap sets the MaxCost parameter, which can be interpreted as the cache capacity.
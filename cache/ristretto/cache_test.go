@@ -1,9 +1,13 @@
 package ristretto
 
 import (
+	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/dgraph-io/ristretto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,3 +46,400 @@ func TestCache_WithoutTTL_TryToGetActualData_ShouldOk(t *testing.T) {
 	assert.Equal(t, true, ok)
 	assert.Equal(t, 1, v)
 }
+
+func TestCache_CostFunc_ShouldBeUsedOnPut(t *testing.T) {
+	config := BuildConfig(10, 0)
+	config.Config.MaxCost = 100
+	config.CostFunc = func(value interface{}) int64 { return int64(len(value.(string))) }
+	c, err := NewWithConfig("test", config)
+	require.NoError(t, err)
+
+	// act
+	c.Put(1, "hello")
+	c.Put(2, "world!")
+	c.cache.Wait()
+
+	// assert
+	assert.Equal(t, int64(11), c.Cost())
+}
+
+func TestCache_PutWithCost_TooBigValue_ShouldBeRejected(t *testing.T) {
+	c, err := NewWithConfig("test", BuildSizeConfig(10, 10, 0))
+	require.NoError(t, err)
+
+	// act
+	c.PutWithCost(1, 1, 5)
+	c.PutWithCost(2, 2, 100)
+	c.cache.Wait()
+
+	// assert
+	assert.True(t, c.Contains(1))
+	assert.False(t, c.Contains(2))
+	assert.Equal(t, int64(5), c.Cost())
+}
+
+func TestCache_Len_ShouldBeAccurate(t *testing.T) {
+	c, err := New("test", 100, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		c.Put(i, i)
+	}
+	c.PutWithCost(0, 0, 5) // update with a different cost
+	c.cache.Wait()
+
+	// act
+	c.Remove(1)
+	c.Remove(2)
+
+	// assert
+	assert.Equal(t, 8, c.Len())
+	assert.ElementsMatch(t, []interface{}{0, 3, 4, 5, 6, 7, 8, 9}, c.Keys())
+}
+
+func TestCache_Len_ShouldFollowEvictions(t *testing.T) {
+	var evicted []interface{}
+	config := BuildConfig(10, 0)
+	config.Config.OnEvict = func(item *ristretto.Item) {
+		evicted = append(evicted, item.Value)
+	}
+	c, err := NewWithConfig("test", config)
+	require.NoError(t, err)
+
+	// act
+	for i := 0; i < 100; i++ {
+		c.Put(i, i)
+		c.cache.Wait()
+	}
+
+	// assert
+	assert.LessOrEqual(t, c.Len(), 10)
+	assert.Len(t, c.Keys(), c.Len())
+	for _, k := range c.Keys() {
+		assert.True(t, c.Contains(k))
+	}
+	require.NotEmpty(t, evicted)
+	assert.IsType(t, 0, evicted[0])
+}
+
+func TestCache_Clear_ShouldResetLen(t *testing.T) {
+	c, err := New("test", 100, 0)
+	require.NoError(t, err)
+	c.Put(1, 1)
+	c.cache.Wait()
+
+	// act
+	c.Clear()
+
+	// assert
+	assert.Equal(t, 0, c.Len())
+	assert.Empty(t, c.Keys())
+}
+
+func TestCache_PutWithSoftTTL_ShouldServeStaleUntilHardTTL(t *testing.T) {
+	c, err := New("test", 10, 0)
+	require.NoError(t, err)
+	c.PutWithSoftTTL(1, 1, 50*time.Millisecond, 100*time.Millisecond)
+	c.cache.Wait()
+
+	v, stale, ok := c.GetStale(1)
+	require.True(t, ok)
+	assert.False(t, stale)
+	assert.Equal(t, 1, v)
+
+	// act
+	time.Sleep(60 * time.Millisecond)
+
+	// assert
+	_, ok = c.Get(1)
+	assert.False(t, ok)
+	_, ok = c.Peek(1)
+	assert.False(t, ok)
+	v, stale, ok = c.GetStale(1)
+	require.True(t, ok)
+	assert.True(t, stale)
+	assert.Equal(t, 1, v)
+
+	time.Sleep(50 * time.Millisecond)
+	_, _, ok = c.GetStale(1)
+	assert.False(t, ok)
+}
+
+func TestCache_PutWithComputeTime_ShouldReturnExpirationAndComputeTime(t *testing.T) {
+	c, err := New("test", 10, 0)
+	require.NoError(t, err)
+	before := time.Now()
+
+	// act
+	c.PutWithComputeTime(1, 1, time.Minute, 10*time.Millisecond)
+	c.cache.Wait()
+
+	// assert
+	v, expires, computeTime, ok := c.GetWithComputeTime(1)
+	require.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 10*time.Millisecond, computeTime)
+	assert.WithinDuration(t, before.Add(time.Minute), expires, time.Second)
+}
+
+func TestCache_PutWithTTL_WithJitter_ShouldSpreadExpiration(t *testing.T) {
+	c, err := NewWithConfig("test", jitterConfig())
+	require.NoError(t, err)
+	before := time.Now()
+
+	// act
+	c.PutWithTTL(1, 1, time.Minute)
+	c.PutWithTTL(2, 2, time.Minute)
+	c.cache.Wait()
+
+	// assert
+	_, expires1, _, ok := c.GetWithComputeTime(1)
+	require.True(t, ok)
+	_, expires2, _, ok := c.GetWithComputeTime(2)
+	require.True(t, ok)
+	for _, expires := range []time.Time{expires1, expires2} {
+		assert.False(t, expires.Before(before.Add(time.Minute)))
+		assert.False(t, expires.After(time.Now().Add(2*time.Minute)))
+	}
+	assert.NotEqual(t, expires1, expires2)
+}
+
+func TestCache_WithClock_ShouldExpireByClock(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	config := BuildConfig(10, time.Minute)
+	config.Clock = clock
+	c, err := NewWithConfig("test", config)
+	require.NoError(t, err)
+	c.Put(1, 1)
+	c.cache.Wait()
+
+	// act
+	clock.Advance(time.Minute - time.Nanosecond)
+	_, okBefore := c.Get(1)
+	clock.Advance(time.Nanosecond)
+	_, okAfter := c.Get(1)
+
+	// assert
+	assert.True(t, okBefore)
+	assert.False(t, okAfter)
+}
+
+func TestCache_GetWithMeta_ShouldReturnEntryMetadata(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	config := BuildConfig(10, time.Minute)
+	config.Clock = clock
+	c, err := NewWithConfig("test", config)
+	require.NoError(t, err)
+	inserted := clock.Now()
+	c.Put(1, 1)
+	c.cache.Wait()
+	clock.Advance(time.Second)
+	c.Get(1)
+	clock.Advance(time.Second)
+
+	// act
+	meta, ok := c.GetWithMeta(1)
+	peeked, peekOK := c.PeekMeta(1)
+	_, missOK := c.GetWithMeta(2)
+
+	// assert
+	require.True(t, ok)
+	assert.Equal(t, 1, meta.Value)
+	assert.Equal(t, inserted, meta.Inserted)
+	assert.Equal(t, inserted.Add(time.Minute), meta.Expires)
+	assert.True(t, meta.Accessed.Equal(inserted.Add(2*time.Second)))
+	assert.Equal(t, uint64(2), meta.Hits)
+	assert.Equal(t, 58*time.Second, meta.Remaining(clock.Now()))
+	require.True(t, peekOK)
+	assert.Equal(t, meta.Hits, peeked.Hits)
+	assert.False(t, missOK)
+}
+
+func TestCache_BatchOperations_ShouldWorkLikeSingleOnes(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	config := BuildConfig(10, time.Minute)
+	config.Clock = clock
+	c, err := NewWithConfig("test", config)
+	require.NoError(t, err)
+	c.PutMany([]cache.KeyValue{{Key: 1, Value: "a"}, {Key: 2, Value: "b"}, {Key: 3, Value: "c"}})
+	c.PutWithTTL(4, "d", time.Second)
+	c.cache.Wait()
+	clock.Advance(2 * time.Second)
+
+	// act
+	hits, missing := c.GetMany([]interface{}{1, 2, 4, 5})
+	c.RemoveMany([]interface{}{1, 2})
+	c.cache.Wait()
+
+	// assert
+	assert.Equal(t, map[interface{}]interface{}{1: "a", 2: "b"}, hits)
+	assert.Equal(t, []interface{}{4, 5}, missing)
+	assert.False(t, c.Contains(1))
+	assert.False(t, c.Contains(2))
+	assert.True(t, c.Contains(3))
+}
+
+func TestCache_RemoveByPrefix_ShouldRemoveMatchingKeys(t *testing.T) {
+	for _, keyIndex := range []bool{false, true} {
+		config := BuildConfig(10, 0)
+		config.KeyIndex = keyIndex
+		c, err := NewWithConfig("test", config)
+		require.NoError(t, err)
+		c.Put("user:42:orders", 1)
+		c.Put("user:42:cart", 2)
+		c.Put("user:43:cart", 3)
+		c.Put(42, 4)
+		c.cache.Wait()
+
+		// act
+		removed := c.RemoveByPrefix("user:42:")
+		matched := c.RemoveMatching(func(key interface{}) bool {
+			_, ok := key.(int)
+			return ok
+		})
+		c.cache.Wait()
+
+		// assert
+		assert.Equal(t, 2, removed)
+		assert.Equal(t, 1, matched)
+		assert.Equal(t, []interface{}{"user:43:cart"}, c.Keys())
+	}
+}
+
+func TestCache_KeyIndex_ShouldForgetClearedKeys(t *testing.T) {
+	config := BuildConfig(10, 0)
+	config.KeyIndex = true
+	c, err := NewWithConfig("test", config)
+	require.NoError(t, err)
+	c.Put("a:1", 1)
+	c.Put("a:2", 2)
+	c.cache.Wait()
+	c.Clear()
+	c.Put("a:3", 3)
+	c.cache.Wait()
+
+	// act
+	removed := c.RemoveByPrefix("a:")
+	c.cache.Wait()
+
+	// assert
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 0, c.Len())
+}
+
+func jitterConfig() Config {
+	config := BuildConfig(10, 0)
+	config.TTLJitter = time.Minute
+	config.RandSource = rand.NewSource(1)
+	return config
+}
+
+func TestCache_Update_ShouldNotLoseConcurrentUpdates(t *testing.T) {
+	c, err := New("test", 10, 0)
+	require.NoError(t, err)
+
+	// act
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Update("counter", func(old interface{}, exists bool) (interface{}, bool) {
+				if !exists {
+					return 1, true
+				}
+				return old.(int) + 1, true
+			})
+		}()
+	}
+	wg.Wait()
+
+	// assert
+	actual, loaded := c.PutIfAbsent("counter", 0)
+	assert.True(t, loaded)
+	assert.Equal(t, 50, actual)
+}
+
+func TestCache_AtomicOperations_ShouldTreatExpiredAsAbsent(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	config := BuildConfig(10, time.Minute)
+	config.Clock = clock
+	c, err := NewWithConfig("test", config)
+	require.NoError(t, err)
+
+	// act
+	_, loaded := c.PutIfAbsent(1, "a")
+	swapped := c.CompareAndSwap(1, "a", "b")
+	notSwapped := c.CompareAndSwap(1, "a", "c")
+	clock.Advance(time.Minute)
+	expiredSwapped := c.CompareAndSwap(1, "b", "d")
+	actual, loadedExpired := c.PutIfAbsent(1, "e")
+	_, kept := c.Update(1, func(interface{}, bool) (interface{}, bool) {
+		return nil, false
+	})
+	c.cache.Wait()
+
+	// assert
+	assert.False(t, loaded)
+	assert.True(t, swapped)
+	assert.False(t, notSwapped)
+	assert.False(t, expiredSwapped)
+	assert.False(t, loadedExpired)
+	assert.Equal(t, "e", actual)
+	assert.False(t, kept)
+	assert.False(t, c.Contains(1))
+}
+
+func TestCache_PutIfVersion_ShouldDetectReplacedEntries(t *testing.T) {
+	c, err := New("test", 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, c.PutIfVersion(1, "a", 0))
+	c.cache.Wait()
+	_, v1, ok := c.GetVersioned(1)
+	require.True(t, ok)
+
+	// act
+	c.Put(1, "b")
+	c.cache.Wait()
+	_, v2, _ := c.GetVersioned(1)
+	errStale := c.PutIfVersion(1, "c", v1)
+	errFresh := c.PutIfVersion(1, "d", v2)
+	errAbsent := c.PutIfVersion(2, "e", v2)
+	c.cache.Wait()
+
+	// assert
+	assert.Greater(t, v2, v1)
+	var mismatch *cache.VersionMismatchError
+	require.ErrorAs(t, errStale, &mismatch)
+	assert.Equal(t, v1, mismatch.Expected)
+	assert.Equal(t, v2, mismatch.Actual)
+	assert.NoError(t, errFresh)
+	assert.ErrorIs(t, errAbsent, cache.ErrVersionMismatch)
+	v, _ := c.Peek(1)
+	assert.Equal(t, "d", v)
+}
+
+func TestCache_ConcurrentPuts_ShouldIndexLatestVersion(t *testing.T) {
+	c, err := New("test", 10, 0)
+	require.NoError(t, err)
+
+	// act
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Put(1, j)
+			}
+		}()
+	}
+	wg.Wait()
+	c.cache.Wait()
+
+	// assert
+	hash, _ := c.keyToHash(1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Equal(t, c.version.Load(), c.index[hash].version)
+}
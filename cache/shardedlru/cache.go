@@ -0,0 +1,568 @@
+package shardedlru
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/metrics"
+)
+
+var (
+	_ cache.NamedCache        = &Cache{}
+	_ cache.WithTTLPutter     = &Cache{}
+	_ cache.KeysGetter        = &Cache{}
+	_ cache.CapSetter         = &Cache{}
+	_ cache.SoftTTLPutter     = &Cache{}
+	_ cache.StaleGetter       = &Cache{}
+	_ cache.TTLGetter         = &Cache{}
+	_ cache.ComputeTimePutter = &Cache{}
+	_ cache.ComputeTimeGetter = &Cache{}
+	_ cache.BatchGetter       = &Cache{}
+	_ cache.BatchPutter       = &Cache{}
+	_ cache.PrefixRemover     = &Cache{}
+	_ cache.AtomicUpdater     = &Cache{}
+	_ cache.Versioner         = &Cache{}
+	_ io.Closer               = &Cache{}
+)
+
+// Cache is a native LRU cache split into lock-striped shards.
+// Every shard is an independent LRU list with its own mutex, so concurrent calls
+// for keys of different shards do not contend, and Get does not allocate on a hit.
+// The recency order is kept per shard, hence the least recently used key is evicted within its shard.
+type Cache struct {
+	shards []*shard
+	mask   uint64
+	hasher hasher
+
+	close   chan struct{}
+	metrics *metrics.CacheMetrics
+	name    string
+	cap     atomic.Int64
+	ttl     time.Duration
+	jitter  *cache.Jitter
+	// keys is the index of the string keys, nil if it is disabled (see WithKeyIndex)
+	keys  *cache.KeyIndex
+	clock cache.Clock
+	// version is the version of the last write, shared by the shards (see PutIfVersion)
+	version atomic.Uint64
+}
+
+const (
+	calcItemNumberInterval = 15 * time.Second
+	shardsPerProc          = 4
+)
+
+// NewCache creates a new sharded LRU cache with capacity and ttl.
+// If ttl == 0, the cache will be without ttl.
+func NewCache(name string, cap int, ttl time.Duration, opts ...Option) (*Cache, error) {
+	oo := &options{
+		shards: runtime.GOMAXPROCS(0) * shardsPerProc,
+		clock:  cache.SystemClock,
+	}
+	for _, o := range opts {
+		o(oo)
+	}
+	if cap <= 0 {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, cache.ErrWrongCapacity)
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, cache.ErrWrongTTL)
+	}
+	jitter, err := cache.NewJitter(oo.jitter, oo.jitterPercent, oo.randSource)
+	if err != nil {
+		return nil, fmt.Errorf("can't create cache %s: %w", name, err)
+	}
+
+	n := shardCount(oo.shards, cap)
+	c := &Cache{
+		shards:  make([]*shard, n),
+		mask:    uint64(n - 1),
+		hasher:  newHasher(oo.hash),
+		name:    name,
+		ttl:     ttl,
+		close:   make(chan struct{}),
+		metrics: metrics.NewCacheMetrics(name),
+		jitter:  jitter,
+		clock:   oo.clock,
+	}
+	c.cap.Store(int64(cap))
+	onEvict := oo.onEvict
+	if oo.keyIndex {
+		c.keys = cache.NewKeyIndex()
+		onEvict = c.keys.OnEvict(onEvict)
+	}
+	for i := range c.shards {
+		c.shards[i] = newShard(shardCap(cap, n, i), onEvict, c.keys, &c.version)
+	}
+
+	go c.stats()
+
+	return c, nil
+}
+
+// shardCount rounds n up to a power of two, but keeps at least one slot per shard.
+func shardCount(n, cap int) int {
+	if n < 1 {
+		n = 1
+	}
+	count := 1
+	for count < n {
+		count <<= 1
+	}
+	for count > 1 && count > cap {
+		count >>= 1
+	}
+	return count
+}
+
+// shardCap spreads the capacity over the shards, so the total capacity is exactly cap.
+func shardCap(cap, shards, i int) int {
+	c := cap / shards
+	if i < cap%shards {
+		c++
+	}
+	return c
+}
+
+func (c *Cache) shard(key interface{}) *shard {
+	return c.shards[c.hasher.hash(key)&c.mask]
+}
+
+// Cap returns the cache capacity.
+func (c *Cache) Cap() int {
+	return int(c.cap.Load())
+}
+
+// TTL returns the default TTL of the cache, 0 means no TTL.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Len returns the number of items in the cache.
+func (c *Cache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.len()
+	}
+	return n
+}
+
+// Clear completely clears the cache.
+func (c *Cache) Clear() {
+	for _, s := range c.shards {
+		s.purge()
+	}
+}
+
+// Close completely clears the cache.
+// Always call Close after finishing using the cache to release resources.
+func (c *Cache) Close() error {
+	select {
+	case _, ok := <-c.close:
+		if !ok {
+			return nil
+		}
+	default:
+	}
+
+	c.Clear()
+	close(c.close)
+
+	return nil
+}
+
+// Contains checks for the presence of a not expired key in the cache without updating the access time.
+func (c *Cache) Contains(key interface{}) bool {
+	_, ok := c.shard(key).peek(key, c.clock.Now())
+	return ok
+}
+
+// Put puts a key-value pair into the cache.
+// It uses the default TTL specified in NewCache.
+func (c *Cache) Put(key, value interface{}) {
+	c.PutWithTTL(key, value, c.ttl)
+}
+
+// PutWithTTL adds a key-value pair to the cache with a specified TTL.
+// If ttl <= 0, no TTL is added.
+func (c *Cache) PutWithTTL(key, value interface{}, ttl time.Duration) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = start.Add(ttl + c.jitter.Duration(ttl))
+	}
+
+	c.shard(key).add(key, value, expires, time.Time{}, 0)
+}
+
+// PutWithComputeTime adds a key-value pair to the cache with a specified TTL and the time it took to compute the value,
+// which is used by the probabilistic early expiration (see cache.WithXFetch).
+func (c *Cache) PutWithComputeTime(key, value interface{}, ttl, computeTime time.Duration) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = start.Add(ttl + c.jitter.Duration(ttl))
+	}
+
+	c.shard(key).add(key, value, expires, time.Time{}, computeTime)
+}
+
+// PutWithSoftTTL adds a key-value pair to the cache with the soft and hard TTLs.
+// After softTTL the entry is stale: Get and Peek treat it as expired, GetStale returns it until hardTTL.
+// If softTTL <= 0, the entry never becomes stale. If hardTTL <= 0, no hard TTL is added.
+func (c *Cache) PutWithSoftTTL(key, value interface{}, softTTL, hardTTL time.Duration) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	// the same jitter is added to the soft TTL to keep the stale period
+	jitter := c.jitter.Duration(hardTTL)
+	hardTTL += jitter
+	if softTTL > 0 {
+		softTTL += jitter
+	}
+
+	var expires, stale time.Time
+	if hardTTL > 0 {
+		expires = start.Add(hardTTL)
+	}
+	if softTTL > 0 && (hardTTL <= 0 || softTTL < hardTTL) {
+		stale = start.Add(softTTL)
+	}
+
+	c.shard(key).add(key, value, expires, stale, 0)
+}
+
+// Get retrieves a value by a specific key from the cache,
+// and returns a boolean indicating whether the value was found.
+// If the TTL of the key has expired, the key is removed and it returns nil and false.
+// A stale key (see PutWithSoftTTL) is reported as expired, but kept until its hard TTL.
+func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	r := c.shard(key).get(key, start, false)
+	expired = r.expired
+	return r.value, r.ok
+}
+
+// GetStale retrieves a value by key like Get, but also returns the entries which soft TTL has passed
+// (see PutWithSoftTTL), stale reports whether the soft TTL of the entry has passed.
+func (c *Cache) GetStale(key interface{}) (value interface{}, stale bool, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok && stale {
+			c.metrics.StaleCount.Inc()
+		} else if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	r := c.shard(key).get(key, start, true)
+	expired = r.expired
+	return r.value, r.stale, r.ok
+}
+
+// GetWithComputeTime retrieves a value by key like Get, with its expiration time and the time it took to compute it
+// (see PutWithComputeTime).
+func (c *Cache) GetWithComputeTime(key interface{}) (value interface{}, expires time.Time, computeTime time.Duration, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	r := c.shard(key).get(key, start, false)
+	expired = r.expired
+	return r.value, r.expires, r.computeTime, r.ok
+}
+
+// Peek retrieves a value by key without updating the access time,
+// and returns a boolean indicating whether the value was found.
+// If the TTL of the key has expired, it returns nil and false.
+func (c *Cache) Peek(key interface{}) (value interface{}, ok bool) {
+	return c.shard(key).peek(key, c.clock.Now())
+}
+
+// Remove removes a value by key from the cache.
+func (c *Cache) Remove(key interface{}) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.shard(key).remove(key)
+}
+
+// GetMany retrieves the values by keys like Get, it returns the found values by key
+// and the keys which are missing or expired, in the order of keys. The metrics are recorded once per batch,
+// the shards are locked per key as the keys are spread across them.
+func (c *Cache) GetMany(keys []interface{}) (hits map[interface{}]interface{}, missing []interface{}) {
+	start := c.clock.Now()
+	hit, expired := 0, 0
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		c.metrics.HitCount.Add(float64(hit))
+		c.metrics.ExpiredCount.Add(float64(expired))
+		c.metrics.MissCount.Add(float64(len(missing) - expired))
+	}()
+
+	hits = make(map[interface{}]interface{}, len(keys))
+	for _, key := range keys {
+		r := c.shard(key).get(key, start, false)
+		if r.ok {
+			hits[key] = r.value
+			hit++
+			continue
+		}
+		if r.expired {
+			expired++
+		}
+		missing = append(missing, key)
+	}
+	return hits, missing
+}
+
+// PutMany adds the key-value pairs to the cache with the default TTL, in the order of items.
+// The metrics are recorded once per batch.
+func (c *Cache) PutMany(items []cache.KeyValue) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	for _, item := range items {
+		var expires time.Time
+		if c.ttl > 0 {
+			expires = start.Add(c.ttl + c.jitter.Duration(c.ttl))
+		}
+		c.shard(item.Key).add(item.Key, item.Value, expires, time.Time{}, 0)
+	}
+}
+
+// RemoveMany removes the values by keys from the cache, the metrics are recorded once per batch.
+func (c *Cache) RemoveMany(keys []interface{}) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeDelete.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	for _, key := range keys {
+		c.shard(key).remove(key)
+	}
+}
+
+// RemoveByPrefix removes the entries which keys are strings with the prefix, it returns the number of removed entries.
+// With WithKeyIndex the keys are found in the index, otherwise all keys are scanned.
+func (c *Cache) RemoveByPrefix(prefix string) int {
+	var keys []interface{}
+	if c.keys != nil {
+		keys = c.keys.KeysWithPrefix(prefix)
+	} else {
+		keys = cache.FilterKeys(c.Keys(), cache.HasPrefix(prefix))
+	}
+	c.RemoveMany(keys)
+	return len(keys)
+}
+
+// RemoveMatching removes the entries which keys match the predicate, it returns the number of removed entries.
+// All keys are scanned.
+func (c *Cache) RemoveMatching(match func(key interface{}) bool) int {
+	keys := cache.FilterKeys(c.Keys(), match)
+	c.RemoveMany(keys)
+	return len(keys)
+}
+
+// PutIfAbsent puts the value with the default TTL if the key is absent, expired or stale,
+// it returns the current value of the key and whether it was loaded.
+func (c *Cache) PutIfAbsent(key, value interface{}) (actual interface{}, loaded bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.shard(key).update(key, start, c.expiration(start), func(old interface{}, _ uint64, exists bool) (interface{}, bool, bool) {
+		if exists {
+			actual, loaded = old, true
+			return nil, false, false
+		}
+		actual = value
+		return value, true, false
+	})
+	return actual, loaded
+}
+
+// CompareAndSwap replaces the value of the key with newValue and the default TTL if the current value equals oldValue,
+// it reports whether the value was swapped. The absent, expired and stale keys are not swapped, the values must be comparable.
+func (c *Cache) CompareAndSwap(key, oldValue, newValue interface{}) (swapped bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.shard(key).update(key, start, c.expiration(start), func(old interface{}, _ uint64, exists bool) (interface{}, bool, bool) {
+		swapped = exists && old == oldValue
+		return newValue, swapped, false
+	})
+	return swapped
+}
+
+// Update calls fn with the current value of the key (exists is false if it is absent, expired or stale)
+// and puts the returned value with the default TTL if keep is true, otherwise it removes the key.
+// It returns the new value and keep. fn is called under the shard lock, so it must not call the cache.
+func (c *Cache) Update(
+	key interface{},
+	fn func(old interface{}, exists bool) (value interface{}, keep bool),
+) (value interface{}, ok bool) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.shard(key).update(key, start, c.expiration(start), func(old interface{}, _ uint64, exists bool) (interface{}, bool, bool) {
+		value, ok = fn(old, exists)
+		return value, ok, !ok
+	})
+	if !ok {
+		return nil, false
+	}
+	return value, true
+}
+
+// GetVersioned retrieves a value by key like Get, with the version of the entry (see PutIfVersion).
+func (c *Cache) GetVersioned(key interface{}) (value interface{}, version uint64, ok bool) {
+	start := c.clock.Now()
+	expired := false
+
+	defer func() {
+		c.metrics.ResponseTimeGet.Observe(metrics.Seconds(c.clock.Since(start)))
+		if ok {
+			c.metrics.HitCount.Inc()
+		} else if expired {
+			c.metrics.ExpiredCount.Inc()
+		} else {
+			c.metrics.MissCount.Inc()
+		}
+	}()
+
+	r := c.shard(key).get(key, start, false)
+	expired = r.expired
+	return r.value, r.version, r.ok
+}
+
+// PutIfVersion puts the value with the default TTL if the version of the key is expectedVersion,
+// 0 means the key is absent, expired or stale. Otherwise it returns *cache.VersionMismatchError.
+func (c *Cache) PutIfVersion(key, value interface{}, expectedVersion uint64) (err error) {
+	start := c.clock.Now()
+
+	defer func() {
+		c.metrics.ResponseTimeSet.Observe(metrics.Seconds(c.clock.Since(start)))
+	}()
+
+	c.shard(key).update(key, start, c.expiration(start), func(_ interface{}, version uint64, _ bool) (interface{}, bool, bool) {
+		if version != expectedVersion {
+			err = &cache.VersionMismatchError{Key: key, Expected: expectedVersion, Actual: version}
+			return nil, false, false
+		}
+		return value, true, false
+	})
+	return err
+}
+
+// expiration returns the expiration time of the entry put at now with the default TTL.
+func (c *Cache) expiration(now time.Time) time.Time {
+	if c.ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(c.ttl + c.jitter.Duration(c.ttl))
+}
+
+// Name returns the cache name.
+func (c *Cache) Name() string {
+	return c.name
+}
+
+// Keys returns a list of saved keys.
+// Keys are ordered from the oldest to the newest within a shard, shards follow one another.
+func (c *Cache) Keys() []interface{} {
+	keys := make([]interface{}, 0, c.Len())
+	for _, s := range c.shards {
+		keys = append(keys, s.keys()...)
+	}
+	return keys
+}
+
+// SetCap sets the capacity of the cache to cap, evicting the least recently used items of the shards above their new capacity.
+// A cap below the number of the shards is rounded up to it, so every shard keeps at least one slot (see Cap).
+func (c *Cache) SetCap(cap int) error {
+	if cap <= 0 {
+		return cache.ErrWrongCapacity
+	}
+	cap = max(cap, len(c.shards))
+	for i, s := range c.shards {
+		s.resize(shardCap(cap, len(c.shards), i))
+	}
+	c.cap.Store(int64(cap))
+
+	return nil
+}
+
+func (c *Cache) stats() {
+	for {
+		select {
+		case <-c.clock.After(calcItemNumberInterval):
+		case <-c.close:
+			return
+		}
+		c.metrics.ItemNumber.Set(float64(c.Len()))
+	}
+}
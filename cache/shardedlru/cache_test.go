@@ -0,0 +1,432 @@
+package shardedlru_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/shardedlru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_PutWithTTL_TryToGetExpiredData_ShouldFail(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 1, 1*time.Second)
+	require.NoError(t, err)
+	c.PutWithTTL(1, 1, time.Nanosecond)
+
+	time.Sleep(time.Nanosecond)
+	_, ok := c.Get(1)
+
+	assert.Equal(t, false, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCache_PutWithTTL_TryToGetActualData_ShouldOk(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 1, 1*time.Second)
+	require.NoError(t, err)
+	c.PutWithTTL(1, 1, 1*time.Second)
+
+	v, ok := c.Get(1)
+
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 1, v)
+}
+
+func TestCache_WithoutTTL_TryToGetActualData_ShouldOk(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 1, 0)
+	require.NoError(t, err)
+	c.Put(1, 1)
+
+	v, ok := c.Get(1)
+
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 1, v)
+}
+
+func TestCache_SingleShard_EvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []interface{}
+	c, err := shardedlru.NewCache("test", 3, 0,
+		shardedlru.WithShards(1),
+		shardedlru.WithEvictCallback(func(key, _ interface{}) { evicted = append(evicted, key) }),
+	)
+	require.NoError(t, err)
+
+	c.Put(1, 1)
+	c.Put(2, 2)
+	c.Put(3, 3)
+	c.Get(1)
+
+	// act
+	c.Put(4, 4)
+
+	// assert
+	assert.Equal(t, []interface{}{2}, evicted)
+	assert.Equal(t, []interface{}{3, 1, 4}, c.Keys())
+}
+
+func TestCache_Keys_ShouldOK(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 100, 0, shardedlru.WithShards(8))
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		c.Put(i, i)
+	}
+
+	// act
+	gotKeys := c.Keys()
+
+	// assert
+	sort.Slice(gotKeys, func(i, j int) bool { return gotKeys[i].(int) < gotKeys[j].(int) })
+	assert.Equal(t, []interface{}{0, 1, 2, 3, 4}, gotKeys)
+}
+
+func TestCache_SetCap_ShouldEvict(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 100, 0, shardedlru.WithShards(4))
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		c.Put(i, i)
+	}
+
+	// act
+	err = c.SetCap(10)
+
+	// assert
+	require.NoError(t, err)
+	assert.Equal(t, 10, c.Cap())
+	assert.LessOrEqual(t, c.Len(), 10)
+}
+
+func TestCache_Get_ShouldNotAllocate(t *testing.T) {
+	for _, key := range []interface{}{"key", 42, 4.2, complex(1, 2), [16]byte{1, 2, 3}} {
+		c, err := shardedlru.NewCache("test", 10, time.Minute)
+		require.NoError(t, err)
+		c.Put(key, 1)
+
+		// act
+		allocs := testing.AllocsPerRun(100, func() {
+			c.Get(key)
+		})
+
+		// assert
+		assert.Zero(t, allocs, key)
+	}
+}
+
+func TestCache_WithHasher_ShouldHashOtherKeys(t *testing.T) {
+	type userKey struct {
+		tenant string
+		id     int
+	}
+	var calls int
+	c, err := shardedlru.NewCache("test", 100, 0, shardedlru.WithShards(8), shardedlru.WithHasher(func(key interface{}) uint64 {
+		calls++
+		return uint64(key.(userKey).id)
+	}))
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		c.Put(userKey{tenant: "a", id: i}, i)
+	}
+	var key interface{} = userKey{tenant: "a", id: 5}
+
+	// act
+	v, ok := c.Get(key)
+	allocs := testing.AllocsPerRun(100, func() {
+		c.Get(key)
+	})
+
+	// assert
+	require.True(t, ok)
+	assert.Equal(t, 5, v)
+	assert.Zero(t, allocs)
+	assert.Greater(t, calls, 10)
+}
+
+func TestCache_CompositeKeys_ShouldBeFoundWithoutHasher(t *testing.T) {
+	type userKey struct {
+		tenant string
+		id     int
+	}
+	c, err := shardedlru.NewCache("test", 100, 0, shardedlru.WithShards(8))
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		c.Put(userKey{tenant: "a", id: i}, i)
+	}
+
+	// act
+	v, ok := c.Get(userKey{tenant: "a", id: 5})
+	c.Put(complex(0, 0), "zero")
+	zero, zeroOk := c.Get(complex(math.Copysign(0, -1), 0))
+
+	// assert
+	require.True(t, ok)
+	assert.Equal(t, 5, v)
+	assert.True(t, zeroOk)
+	assert.Equal(t, "zero", zero)
+}
+
+func TestCache_SetCap_BelowShardCount_ShouldKeepSlotPerShard(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 64, 0, shardedlru.WithShards(64))
+	require.NoError(t, err)
+
+	// act
+	err = c.SetCap(8)
+	hits := 0
+	for i := 0; i < 1000; i++ {
+		c.Put(i, i)
+		if _, ok := c.Get(i); ok {
+			hits++
+		}
+	}
+
+	// assert
+	require.NoError(t, err)
+	assert.Equal(t, 64, c.Cap())
+	assert.Equal(t, 1000, hits)
+	assert.LessOrEqual(t, c.Len(), 64)
+}
+
+func TestCache_Concurrent_ShouldNotExceedCap(t *testing.T) {
+	const capacity = 64
+	c, err := shardedlru.NewCache("test", capacity, 0, shardedlru.WithShards(8))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.Put(w*1000+i, i)
+				c.Get(w*1000 + i/2)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, c.Len(), capacity)
+}
+
+func TestCache_PutWithSoftTTL_ShouldServeStaleUntilHardTTL(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 2, 0)
+	require.NoError(t, err)
+	c.PutWithSoftTTL(1, 1, 50*time.Millisecond, 100*time.Millisecond)
+
+	v, stale, ok := c.GetStale(1)
+	require.True(t, ok)
+	assert.False(t, stale)
+	assert.Equal(t, 1, v)
+
+	// act
+	time.Sleep(60 * time.Millisecond)
+
+	// assert
+	_, ok = c.Get(1)
+	assert.False(t, ok)
+	_, ok = c.Peek(1)
+	assert.False(t, ok)
+	v, stale, ok = c.GetStale(1)
+	require.True(t, ok)
+	assert.True(t, stale)
+	assert.Equal(t, 1, v)
+
+	time.Sleep(50 * time.Millisecond)
+	_, _, ok = c.GetStale(1)
+	assert.False(t, ok)
+}
+
+func TestCache_PutWithComputeTime_ShouldReturnExpirationAndComputeTime(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 2, 0)
+	require.NoError(t, err)
+	before := time.Now()
+
+	// act
+	c.PutWithComputeTime(1, 1, time.Minute, 10*time.Millisecond)
+
+	// assert
+	v, expires, computeTime, ok := c.GetWithComputeTime(1)
+	require.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 10*time.Millisecond, computeTime)
+	assert.WithinDuration(t, before.Add(time.Minute), expires, time.Second)
+}
+
+func TestCache_PutWithTTL_WithJitter_ShouldSpreadExpiration(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 2, 0, shardedlru.WithTTLJitter(time.Minute), shardedlru.WithRandSource(rand.NewSource(1)))
+	require.NoError(t, err)
+	before := time.Now()
+
+	// act
+	c.PutWithTTL(1, 1, time.Minute)
+	c.PutWithTTL(2, 2, time.Minute)
+
+	// assert
+	_, expires1, _, ok := c.GetWithComputeTime(1)
+	require.True(t, ok)
+	_, expires2, _, ok := c.GetWithComputeTime(2)
+	require.True(t, ok)
+	for _, expires := range []time.Time{expires1, expires2} {
+		assert.False(t, expires.Before(before.Add(time.Minute)))
+		assert.False(t, expires.After(time.Now().Add(2*time.Minute)))
+	}
+	assert.NotEqual(t, expires1, expires2)
+}
+
+func TestCache_WithClock_ShouldExpireByClock(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	c, err := shardedlru.NewCache("test", 2, time.Minute, shardedlru.WithClock(clock))
+	require.NoError(t, err)
+	c.Put(1, 1)
+
+	// act
+	clock.Advance(time.Minute - time.Nanosecond)
+	_, okBefore := c.Get(1)
+	clock.Advance(time.Nanosecond)
+	_, okAfter := c.Get(1)
+
+	// assert
+	assert.True(t, okBefore)
+	assert.False(t, okAfter)
+}
+
+func TestCache_BatchOperations_ShouldWorkLikeSingleOnes(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	c, err := shardedlru.NewCache("test", 10, time.Minute, shardedlru.WithClock(clock), shardedlru.WithShards(1))
+	require.NoError(t, err)
+	c.PutMany([]cache.KeyValue{{Key: 1, Value: "a"}, {Key: 2, Value: "b"}, {Key: 3, Value: "c"}})
+	c.PutWithTTL(4, "d", time.Second)
+	clock.Advance(2 * time.Second)
+
+	// act
+	hits, missing := c.GetMany([]interface{}{1, 2, 4, 5})
+	c.RemoveMany([]interface{}{1, 2})
+
+	// assert
+	assert.Equal(t, map[interface{}]interface{}{1: "a", 2: "b"}, hits)
+	assert.Equal(t, []interface{}{4, 5}, missing)
+	assert.False(t, c.Contains(1))
+	assert.False(t, c.Contains(2))
+	assert.True(t, c.Contains(3))
+}
+
+func TestCache_RemoveByPrefix_ShouldRemoveMatchingKeys(t *testing.T) {
+	// a single shard, so the keys do not evict each other in the small shards
+	for _, opts := range [][]shardedlru.Option{{shardedlru.WithShards(1)}, {shardedlru.WithShards(1), shardedlru.WithKeyIndex()}} {
+		c, err := shardedlru.NewCache("test", 10, 0, opts...)
+		require.NoError(t, err)
+		c.Put("user:42:orders", 1)
+		c.Put("user:42:cart", 2)
+		c.Put("user:43:cart", 3)
+		c.Put(42, 4)
+
+		// act
+		removed := c.RemoveByPrefix("user:42:")
+		matched := c.RemoveMatching(func(key interface{}) bool {
+			_, ok := key.(int)
+			return ok
+		})
+
+		// assert
+		assert.Equal(t, 2, removed)
+		assert.Equal(t, 1, matched)
+		assert.Equal(t, []interface{}{"user:43:cart"}, c.Keys())
+	}
+}
+
+func TestCache_WithKeyIndex_ShouldForgetEvictedKeys(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 1, 0, shardedlru.WithKeyIndex(), shardedlru.WithShards(1))
+	require.NoError(t, err)
+	c.Put("a:1", 1)
+	c.Put("a:2", 2)
+
+	// act
+	removed := c.RemoveByPrefix("a:")
+
+	// assert
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCache_Update_ShouldNotLoseConcurrentUpdates(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 10, 0)
+	require.NoError(t, err)
+
+	// act
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Update("counter", func(old interface{}, exists bool) (interface{}, bool) {
+				if !exists {
+					return 1, true
+				}
+				return old.(int) + 1, true
+			})
+		}()
+	}
+	wg.Wait()
+
+	// assert
+	actual, loaded := c.PutIfAbsent("counter", 0)
+	assert.True(t, loaded)
+	assert.Equal(t, 50, actual)
+}
+
+func TestCache_AtomicOperations_ShouldTreatExpiredAsAbsent(t *testing.T) {
+	clock := cache.NewFakeClock(time.Now())
+	c, err := shardedlru.NewCache("test", 10, time.Minute, shardedlru.WithClock(clock))
+	require.NoError(t, err)
+
+	// act
+	_, loaded := c.PutIfAbsent(1, "a")
+	swapped := c.CompareAndSwap(1, "a", "b")
+	notSwapped := c.CompareAndSwap(1, "a", "c")
+	clock.Advance(time.Minute)
+	expiredSwapped := c.CompareAndSwap(1, "b", "d")
+	actual, loadedExpired := c.PutIfAbsent(1, "e")
+	_, kept := c.Update(1, func(interface{}, bool) (interface{}, bool) {
+		return nil, false
+	})
+
+	// assert
+	assert.False(t, loaded)
+	assert.True(t, swapped)
+	assert.False(t, notSwapped)
+	assert.False(t, expiredSwapped)
+	assert.False(t, loadedExpired)
+	assert.Equal(t, "e", actual)
+	assert.False(t, kept)
+	assert.False(t, c.Contains(1))
+}
+
+func TestCache_PutIfVersion_ShouldDetectReplacedEntries(t *testing.T) {
+	c, err := shardedlru.NewCache("test", 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, c.PutIfVersion(1, "a", 0))
+	_, v1, ok := c.GetVersioned(1)
+	require.True(t, ok)
+
+	// act
+	c.Put(1, "b")
+	_, v2, _ := c.GetVersioned(1)
+	errStale := c.PutIfVersion(1, "c", v1)
+	errFresh := c.PutIfVersion(1, "d", v2)
+	errAbsent := c.PutIfVersion(2, "e", v2)
+
+	// assert
+	assert.Greater(t, v2, v1)
+	var mismatch *cache.VersionMismatchError
+	require.ErrorAs(t, errStale, &mismatch)
+	assert.Equal(t, v1, mismatch.Expected)
+	assert.Equal(t, v2, mismatch.Actual)
+	assert.NoError(t, errFresh)
+	assert.ErrorIs(t, errAbsent, cache.ErrVersionMismatch)
+	v, _ := c.Peek(1)
+	assert.Equal(t, "d", v)
+}
@@ -0,0 +1,105 @@
+package shardedlru
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// hasher distributes keys over the shards.
+// Strings, numbers, booleans and 16-byte arrays (e.g. UUIDs) are hashed without allocations, pointers and channels
+// are hashed by address. The other key types (e.g. structs) are hashed by the custom hash (see WithHasher),
+// or by default by their fmt representation, which allocates and is much slower.
+type hasher struct {
+	seed maphash.Seed
+	// custom hashes the keys of the other types, nil means the fmt representation
+	custom func(key interface{}) uint64
+}
+
+func newHasher(custom func(key interface{}) uint64) hasher {
+	return hasher{seed: maphash.MakeSeed(), custom: custom}
+}
+
+func (h hasher) hash(key interface{}) uint64 {
+	switch k := key.(type) {
+	case string:
+		return maphash.String(h.seed, k)
+	case int:
+		return mix(uint64(k))
+	case int8:
+		return mix(uint64(k))
+	case int16:
+		return mix(uint64(k))
+	case int32:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case uint:
+		return mix(uint64(k))
+	case uint8:
+		return mix(uint64(k))
+	case uint16:
+		return mix(uint64(k))
+	case uint32:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	case uintptr:
+		return mix(uint64(k))
+	case float32:
+		if k == 0 {
+			return mix(0) // +0 and -0 are the same key
+		}
+		return mix(uint64(math.Float32bits(k)))
+	case float64:
+		if k == 0 {
+			return mix(0)
+		}
+		return mix(math.Float64bits(k))
+	case complex64:
+		return hashComplex(float64(real(k)), float64(imag(k)))
+	case complex128:
+		return hashComplex(real(k), imag(k))
+	case bool:
+		if k {
+			return mix(1)
+		}
+		return mix(0)
+	case [16]byte:
+		return maphash.Bytes(h.seed, k[:])
+	default:
+		if v := reflect.ValueOf(k); v.Kind() == reflect.Ptr || v.Kind() == reflect.Chan || v.Kind() == reflect.UnsafePointer {
+			return mix(uint64(v.Pointer()))
+		}
+		if h.custom != nil {
+			return h.custom(k)
+		}
+		// the fmt representation is the same for the equal keys, except the floats +0 and -0 in composite keys
+		var mh maphash.Hash
+		mh.SetSeed(h.seed)
+		_, _ = fmt.Fprintf(&mh, "%#v", k)
+		return mh.Sum64()
+	}
+}
+
+// hashComplex hashes the complex number by its parts, +0 and -0 are the same part.
+func hashComplex(re, im float64) uint64 {
+	if re == 0 {
+		re = 0
+	}
+	if im == 0 {
+		im = 0
+	}
+	return mix(math.Float64bits(re) ^ mix(math.Float64bits(im)))
+}
+
+// mix is the splitmix64 finalizer, it spreads sequential integers over all bits.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
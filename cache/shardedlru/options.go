@@ -0,0 +1,87 @@
+package shardedlru
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+)
+
+type options struct {
+	shards        int
+	onEvict       func(key, value interface{})
+	jitter        time.Duration
+	jitterPercent float64
+	randSource    rand.Source
+	clock         cache.Clock
+	keyIndex      bool
+	hash          func(key interface{}) uint64
+}
+
+// Option configures the sharded LRU cache.
+type Option func(*options)
+
+// WithShards sets the number of shards, it is rounded up to a power of two.
+// By default the cache uses 4 shards per GOMAXPROCS.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithEvictCallback sets a function to be called when an item leaves the cache
+// (evicted by capacity, expired, removed or purged).
+// The callback is called under the shard lock, so it must not call the cache.
+func WithEvictCallback(onEvict func(key, value interface{})) Option {
+	return func(o *options) {
+		o.onEvict = onEvict
+	}
+}
+
+// WithTTLJitter adds a random jitter from 0 to max to the TTL of every entry,
+// so the entries put at the same time do not expire at the same moment.
+func WithTTLJitter(max time.Duration) Option {
+	return func(o *options) {
+		o.jitter = max
+	}
+}
+
+// WithTTLJitterPercent adds a random jitter from 0 to percent of the TTL (e.g. 0.1 for 10%) to the TTL of every entry.
+// It can be combined with WithTTLJitter.
+func WithTTLJitterPercent(percent float64) Option {
+	return func(o *options) {
+		o.jitterPercent = percent
+	}
+}
+
+// WithRandSource sets the random source of the TTL jitter, e.g. a fixed-seed source for deterministic tests.
+func WithRandSource(src rand.Source) Option {
+	return func(o *options) {
+		o.randSource = src
+	}
+}
+
+// WithClock sets the clock of the cache used for the expiration, the metrics timing and the background loops,
+// e.g. a cache.FakeClock for deterministic tests. cache.SystemClock is used by default.
+func WithClock(clock cache.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithKeyIndex keeps the string keys in a radix tree, so RemoveByPrefix finds the keys without scanning all of them.
+// The index costs the memory for the keys and an update on every insertion and removal.
+func WithKeyIndex() Option {
+	return func(o *options) {
+		o.keyIndex = true
+	}
+}
+
+// WithHasher sets the hash used to pick the shard of the keys which are not strings, numbers, booleans, [16]byte,
+// pointers or channels (e.g. structs). By default such keys are hashed by their fmt representation,
+// which allocates on every call. The equal keys must have the same hash.
+func WithHasher(hash func(key interface{}) uint64) Option {
+	return func(o *options) {
+		o.hash = hash
+	}
+}
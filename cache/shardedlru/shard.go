@@ -0,0 +1,257 @@
+package shardedlru
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+)
+
+type node struct {
+	key     interface{}
+	value   interface{}
+	expires time.Time
+	stale   time.Time
+	// computeTime is the time it took to compute the value (see Cache.PutWithComputeTime)
+	computeTime time.Duration
+	// version is the version of the write (see Cache.PutIfVersion)
+	version uint64
+
+	prev, next *node
+}
+
+// lookup is the result of shard.get.
+type lookup struct {
+	value       interface{}
+	expires     time.Time
+	computeTime time.Duration
+	version     uint64
+	ok          bool
+	stale       bool
+	expired     bool
+}
+
+func (n *node) expired(now time.Time) bool {
+	return !n.expires.IsZero() && !now.Before(n.expires)
+}
+
+// isStale reports whether the soft TTL of the node has passed.
+func (n *node) isStale(now time.Time) bool {
+	return !n.stale.IsZero() && !now.Before(n.stale)
+}
+
+// shard is a plain LRU list guarded by its own mutex.
+type shard struct {
+	mu    sync.Mutex
+	items map[interface{}]*node
+	// root is the sentinel of the circular list: root.next is the most recently used node, root.prev is the oldest one.
+	root node
+	cap  int
+
+	onEvict func(key, value interface{})
+	// keyIndex is the index of the string keys of the cache, nil if it is disabled
+	keyIndex *cache.KeyIndex
+	// version is the version of the last write of the cache, shared by the shards
+	version *atomic.Uint64
+}
+
+func newShard(cap int, onEvict func(key, value interface{}), keyIndex *cache.KeyIndex, version *atomic.Uint64) *shard {
+	s := &shard{
+		items:    make(map[interface{}]*node),
+		cap:      cap,
+		onEvict:  onEvict,
+		keyIndex: keyIndex,
+		version:  version,
+	}
+	s.root.next = &s.root
+	s.root.prev = &s.root
+	return s
+}
+
+// get returns the value of the key and marks it as the most recently used.
+// The stale nodes are returned only if withStale is set, otherwise they are reported as expired but kept until the hard TTL.
+func (s *shard) get(key interface{}, now time.Time, withStale bool) lookup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.items[key]
+	if !ok {
+		return lookup{}
+	}
+	if n.expired(now) {
+		s.removeNode(n)
+		return lookup{expired: true}
+	}
+	stale := n.isStale(now)
+	if stale && !withStale {
+		return lookup{expired: true}
+	}
+	s.moveToFront(n)
+	return lookup{value: n.value, expires: n.expires, computeTime: n.computeTime, version: n.version, ok: true, stale: stale}
+}
+
+func (s *shard) peek(key interface{}, now time.Time) (value interface{}, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.items[key]
+	if !ok || n.expired(now) || n.isStale(now) {
+		return nil, false
+	}
+	return n.value, true
+}
+
+func (s *shard) add(key, value interface{}, expires, stale time.Time, computeTime time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addLocked(key, value, expires, stale, computeTime)
+}
+
+// addLocked adds the value to the shard, s.mu must be held.
+func (s *shard) addLocked(key, value interface{}, expires, stale time.Time, computeTime time.Duration) {
+	if n, ok := s.items[key]; ok {
+		n.value = value
+		n.expires = expires
+		n.stale = stale
+		n.computeTime = computeTime
+		n.version = s.version.Add(1)
+		s.moveToFront(n)
+		return
+	}
+	if s.cap <= 0 {
+		return
+	}
+
+	var n *node
+	if len(s.items) >= s.cap {
+		// reuse the evicted node, so a full cache does not allocate on Put
+		n = s.root.prev
+		s.removeNode(n)
+		*n = node{}
+	} else {
+		n = &node{}
+	}
+	n.key = key
+	n.value = value
+	n.expires = expires
+	n.stale = stale
+	n.computeTime = computeTime
+	n.version = s.version.Add(1)
+	s.pushFront(n)
+	s.items[key] = n
+	s.keyIndex.Insert(key)
+}
+
+func (s *shard) remove(key interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLocked(key)
+}
+
+// removeLocked removes the key from the shard, s.mu must be held.
+func (s *shard) removeLocked(key interface{}) {
+	if n, ok := s.items[key]; ok {
+		s.removeNode(n)
+	}
+}
+
+// update calls fn with the fresh value of the key and its version under the lock: the returned value is added
+// with expires if put is set, otherwise the key is removed if remove is set.
+func (s *shard) update(
+	key interface{},
+	now, expires time.Time,
+	fn func(old interface{}, version uint64, exists bool) (value interface{}, put, remove bool),
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var old interface{}
+	var version uint64
+	n, exists := s.items[key]
+	if exists && (n.expired(now) || n.isStale(now)) {
+		exists = false
+	} else if exists {
+		old, version = n.value, n.version
+	}
+
+	value, put, remove := fn(old, version, exists)
+	switch {
+	case put:
+		s.addLocked(key, value, expires, time.Time{}, 0)
+	case remove:
+		s.removeLocked(key)
+	}
+}
+
+func (s *shard) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.onEvict != nil {
+		for n := s.root.prev; n != &s.root; n = n.prev {
+			s.onEvict(n.key, n.value)
+		}
+	}
+	s.items = make(map[interface{}]*node)
+	s.root.next = &s.root
+	s.root.prev = &s.root
+}
+
+func (s *shard) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.items)
+}
+
+// keys returns the keys of the shard from the oldest to the newest.
+func (s *shard) keys() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]interface{}, 0, len(s.items))
+	for n := s.root.prev; n != &s.root; n = n.prev {
+		keys = append(keys, n.key)
+	}
+	return keys
+}
+
+func (s *shard) resize(cap int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cap = cap
+	for len(s.items) > s.cap {
+		s.removeNode(s.root.prev)
+	}
+}
+
+func (s *shard) pushFront(n *node) {
+	n.prev = &s.root
+	n.next = s.root.next
+	n.prev.next = n
+	n.next.prev = n
+}
+
+func (s *shard) moveToFront(n *node) {
+	if s.root.next == n {
+		return
+	}
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	s.pushFront(n)
+}
+
+func (s *shard) removeNode(n *node) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev = nil
+	n.next = nil
+	delete(s.items, n.key)
+	if s.onEvict != nil {
+		s.onEvict(n.key, n.value)
+	}
+}
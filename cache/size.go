@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// SizeEstimator returns the approximate size of the value in bytes.
+// It is used by the caches with a byte budget to compute the cost of the values put without an explicit cost.
+type SizeEstimator func(value interface{}) int64
+
+// EstimateSize is the default SizeEstimator.
+// It uses proto.Size for protobuf messages (the responses cached by the gRPC interceptor),
+// the length for strings and byte slices, and walks other values with reflection,
+// counting the memory referenced through pointers, slices, maps and interfaces once.
+func EstimateSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case proto.Message:
+		return int64(proto.Size(v))
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	}
+
+	rv := reflect.ValueOf(value)
+	seen := make(map[uintptr]struct{})
+	return int64(rv.Type().Size()) + referencedSize(rv, seen)
+}
+
+// referencedSize returns the size of the memory referenced by v, not including the size of v itself.
+func referencedSize(v reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		if v.CanInterface() {
+			if m, ok := v.Interface().(proto.Message); ok {
+				return int64(proto.Size(m))
+			}
+		}
+		e := v.Elem()
+		return int64(e.Type().Size()) + referencedSize(e, seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		return int64(e.Type().Size()) + referencedSize(e, seen)
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			size += referencedSize(v.Index(i), seen)
+		}
+		return size
+	case reflect.Array:
+		var size int64
+		for i := 0; i < v.Len(); i++ {
+			size += referencedSize(v.Index(i), seen)
+		}
+		return size
+	case reflect.Map:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		entrySize := int64(v.Type().Key().Size() + v.Type().Elem().Size())
+		size := int64(v.Len()) * entrySize
+		iter := v.MapRange()
+		for iter.Next() {
+			size += referencedSize(iter.Key(), seen) + referencedSize(iter.Value(), seen)
+		}
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += referencedSize(v.Field(i), seen)
+		}
+		return size
+	default:
+		return 0
+	}
+}
+
+func visited(p uintptr, seen map[uintptr]struct{}) bool {
+	if _, ok := seen[p]; ok {
+		return true
+	}
+	seen[p] = struct{}{}
+	return false
+}
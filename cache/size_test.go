@@ -0,0 +1,51 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEstimateSize(t *testing.T) {
+	t.Parallel()
+
+	type node struct {
+		name string
+		next *node
+	}
+	cyclic := &node{name: "abc"}
+	cyclic.next = cyclic
+
+	msg := wrapperspb.String("hello")
+
+	cases := []struct {
+		name  string
+		value interface{}
+		want  int64
+	}{
+		{name: "nil", value: nil, want: 0},
+		{name: "bytes", value: make([]byte, 100), want: 100},
+		{name: "string", value: "hello", want: 5},
+		{name: "proto message", value: msg, want: int64(proto.Size(msg))},
+		{name: "int", value: 1, want: 8},
+		{name: "slice of strings", value: []string{"ab", "cd"}, want: 24 + 2*16 + 4},
+		{name: "cyclic pointers", value: cyclic, want: 8 + 24 + 3},
+		{name: "struct with proto field", value: struct{ Msg *wrapperspb.StringValue }{msg}, want: 8 + int64(proto.Size(msg))},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// act
+			got := cache.EstimateSize(tc.value)
+
+			// assert
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
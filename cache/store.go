@@ -0,0 +1,576 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/catalystgo/tracerok/logger"
+)
+
+var (
+	// ErrNotFound is the error of Store.Load if the key is not in the store.
+	ErrNotFound = errors.New("not found")
+	// ErrWrongFlushInterval is the write-behind flush interval error if it is not positive.
+	ErrWrongFlushInterval = errors.New("wrong flush interval, it should be positive")
+	// ErrWrongRetry is the store retry error if the number of attempts is not positive or the backoff is negative.
+	ErrWrongRetry = errors.New("wrong retry, attempts should be positive and backoff >= 0")
+)
+
+const (
+	defaultStoreAttempts   = 3
+	defaultStoreBackoff    = 100 * time.Millisecond
+	defaultStoreMaxBackoff = 10 * time.Second
+)
+
+// Store is the backing store (database, service, etc.) of a StoreCache, which owns the persistence of the cached data.
+type Store interface {
+	// Load returns the value for the key, ErrNotFound if the key is not in the store.
+	Load(ctx context.Context, key interface{}) (interface{}, error)
+	// LoadMany returns the values of the keys found in the store.
+	LoadMany(ctx context.Context, keys []interface{}) (map[interface{}]interface{}, error)
+	// Save stores the value for the key.
+	Save(ctx context.Context, key, value interface{}) error
+	// SaveMany stores the key-value pairs at once.
+	SaveMany(ctx context.Context, items []KeyValue) error
+	// Delete deletes the key, deleting an absent key is not an error.
+	Delete(ctx context.Context, key interface{}) error
+	// DeleteMany deletes the keys at once.
+	DeleteMany(ctx context.Context, keys []interface{}) error
+}
+
+// StoreOption is an option of the StoreCache.
+type StoreOption func(*StoreCache)
+
+// WithWriteBehind makes the writes asynchronous: they are put into the cache immediately and queued,
+// the repeated writes of a key are coalesced into the last one, and the queue is flushed to the store every interval
+// or once it has maxBatch keys, in the batches of up to maxBatch keys (0 means one batch).
+// By default the writes go through to the store synchronously.
+func WithWriteBehind(interval time.Duration, maxBatch int) StoreOption {
+	return func(c *StoreCache) {
+		c.writeBehind = true
+		c.flushInterval = interval
+		c.maxBatch = maxBatch
+	}
+}
+
+// WithStoreRetry sets the number of the attempts of the write-behind flushes and the backoff between them,
+// which is doubled after every attempt up to maxBackoff (0 means no limit).
+// By default there are 3 attempts with the backoff from 100ms up to 10s.
+func WithStoreRetry(attempts int, backoff, maxBackoff time.Duration) StoreOption {
+	return func(c *StoreCache) {
+		c.attempts = attempts
+		c.backoff = backoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithStoreClock sets the clock of the flush loop and the retry backoff, e.g. a FakeClock for deterministic tests.
+// SystemClock is used by default.
+func WithStoreClock(clock Clock) StoreOption {
+	return func(c *StoreCache) {
+		c.clock = clock
+	}
+}
+
+// WithStoreErrorf sets the logger of the errors of Put, Remove and the background flushes.
+func WithStoreErrorf(f func(ctx context.Context, format string, args ...interface{})) StoreOption {
+	return func(c *StoreCache) {
+		c.logErrorf = f
+	}
+}
+
+var (
+	_ NamedCache = &StoreCache{}
+	_ io.Closer  = &StoreCache{}
+)
+
+// StoreCache is a cache which owns the persistence of its data: the writes go to the Store through the cache,
+// synchronously (write-through) or queued behind with batching (see WithWriteBehind), and the misses are loaded from it.
+// Put and Remove write to the store too, logging the errors, the other methods of the Cache affect only the cache.
+type StoreCache struct {
+	NamedCache
+	store Store
+
+	writeBehind   bool
+	flushInterval time.Duration
+	maxBatch      int
+	attempts      int
+	backoff       time.Duration
+	maxBackoff    time.Duration
+	clock         Clock
+
+	mu sync.Mutex
+	// pending is the queue of the write-behind writes, coalesced by key
+	pending map[interface{}]storeWrite
+	// flushing is the writes being flushed, Load finds them until the store is written
+	flushing map[interface{}]storeWrite
+	// locks are the locks of the keys being written or loaded, guarded by mu (see lockKeys)
+	locks map[interface{}]*keyLock
+	// batchMu is held exclusively by the operations on several keys, and shared by the ones on a single key
+	batchMu sync.RWMutex
+	// flushMu serializes the flushes, so an older write of a key is never saved after a newer one
+	flushMu sync.Mutex
+	// kick requests a flush of the full queue
+	kick  chan struct{}
+	close chan struct{}
+	done  chan struct{}
+
+	logErrorf func(ctx context.Context, format string, args ...interface{})
+}
+
+// storeWrite is a queued write of a key, the deletion if deleted is set.
+type storeWrite struct {
+	value   interface{}
+	deleted bool
+}
+
+// keyLock is the lock of a key, refs is the number of the goroutines holding or waiting for it.
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewStoreCache creates a StoreCache over the cache c backed by the store.
+// Always call Close after finishing using the cache to flush the queued writes.
+func NewStoreCache(c NamedCache, store Store, opts ...StoreOption) (*StoreCache, error) {
+	sc := &StoreCache{
+		NamedCache: c,
+		store:      store,
+		attempts:   defaultStoreAttempts,
+		backoff:    defaultStoreBackoff,
+		maxBackoff: defaultStoreMaxBackoff,
+		clock:      SystemClock,
+		pending:    make(map[interface{}]storeWrite),
+		locks:      make(map[interface{}]*keyLock),
+		kick:       make(chan struct{}, 1),
+		close:      make(chan struct{}),
+		done:       make(chan struct{}),
+		logErrorf:  logger.Errorf,
+	}
+	for _, opt := range opts {
+		opt(sc)
+	}
+
+	if sc.writeBehind && sc.flushInterval <= 0 {
+		return nil, fmt.Errorf("can't create store cache %s: %w", c.Name(), ErrWrongFlushInterval)
+	}
+	if sc.maxBatch < 0 {
+		return nil, fmt.Errorf("can't create store cache %s: %w", c.Name(), ErrWrongBatchSize)
+	}
+	if sc.attempts <= 0 || sc.backoff < 0 || sc.maxBackoff < 0 {
+		return nil, fmt.Errorf("can't create store cache %s: %w", c.Name(), ErrWrongRetry)
+	}
+
+	if sc.writeBehind {
+		go sc.flushLoop()
+	} else {
+		close(sc.done)
+	}
+
+	return sc, nil
+}
+
+// Load returns the value for the key from the cache, or from the queued writes, or loads it from the store
+// and puts it into the cache. It returns ErrNotFound if the key is not in the store or its deletion is queued.
+// The load from the store is serialized with the writes of the key, so it never puts a value older than the written one.
+func (c *StoreCache) Load(ctx context.Context, key interface{}) (interface{}, error) {
+	if v, ok := c.NamedCache.Get(key); ok {
+		return v, nil
+	}
+
+	unlock := c.lockKeys(key)
+	defer unlock()
+
+	if w, ok := c.queued(key); ok {
+		if w.deleted {
+			return nil, ErrNotFound
+		}
+		return w.value, nil
+	}
+
+	v, err := c.store.Load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.NamedCache.Put(key, v)
+	return v, nil
+}
+
+// LoadMany returns the values of the keys found in the cache, in the queued writes or in the store (see Load),
+// the values loaded from the store are put into the cache.
+func (c *StoreCache) LoadMany(ctx context.Context, keys []interface{}) (map[interface{}]interface{}, error) {
+	var found map[interface{}]interface{}
+	var missing []interface{}
+	if getter, ok := c.NamedCache.(BatchGetter); ok {
+		found, missing = getter.GetMany(keys)
+	} else {
+		found = make(map[interface{}]interface{}, len(keys))
+		for _, key := range keys {
+			if v, ok := c.NamedCache.Get(key); ok {
+				found[key] = v
+				continue
+			}
+			missing = append(missing, key)
+		}
+	}
+
+	unlock := c.lockKeys(missing...)
+	defer unlock()
+
+	toLoad := missing[:0]
+	for _, key := range missing {
+		w, ok := c.queued(key)
+		switch {
+		case !ok:
+			toLoad = append(toLoad, key)
+		case !w.deleted:
+			found[key] = w.value
+		}
+	}
+	if len(toLoad) == 0 {
+		return found, nil
+	}
+
+	loaded, err := c.store.LoadMany(ctx, toLoad)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]KeyValue, 0, len(loaded))
+	for key, v := range loaded {
+		found[key] = v
+		items = append(items, KeyValue{Key: key, Value: v})
+	}
+	c.putMany(items)
+	return found, nil
+}
+
+// Save writes the value of the key to the store and to the cache. With the write-through the store is written first,
+// the cache is not updated if it fails. With the write-behind the write is queued and Save never fails.
+// The writes of a key are serialized, so the cache and the store end up with the same value.
+func (c *StoreCache) Save(ctx context.Context, key, value interface{}) error {
+	unlock := c.lockKeys(key)
+	defer unlock()
+
+	if c.writeBehind {
+		c.NamedCache.Put(key, value)
+		c.enqueue(key, storeWrite{value: value})
+		return nil
+	}
+
+	if err := c.store.Save(ctx, key, value); err != nil {
+		return err
+	}
+	c.NamedCache.Put(key, value)
+	return nil
+}
+
+// SaveMany writes the key-value pairs to the store at once and to the cache (see Save).
+func (c *StoreCache) SaveMany(ctx context.Context, items []KeyValue) error {
+	keys := make([]interface{}, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+	unlock := c.lockKeys(keys...)
+	defer unlock()
+
+	if c.writeBehind {
+		c.putMany(items)
+		for _, item := range items {
+			c.enqueue(item.Key, storeWrite{value: item.Value})
+		}
+		return nil
+	}
+
+	if err := c.store.SaveMany(ctx, items); err != nil {
+		return err
+	}
+	c.putMany(items)
+	return nil
+}
+
+// Delete removes the key from the cache and deletes it from the store (see Save).
+// With the write-through the key is removed from the cache even if the store fails, so the cache never serves a value
+// which could have been deleted.
+func (c *StoreCache) Delete(ctx context.Context, key interface{}) error {
+	unlock := c.lockKeys(key)
+	defer unlock()
+
+	c.NamedCache.Remove(key)
+	if c.writeBehind {
+		c.enqueue(key, storeWrite{deleted: true})
+		return nil
+	}
+
+	return c.store.Delete(ctx, key)
+}
+
+// DeleteMany removes the keys from the cache and deletes them from the store at once (see Delete).
+func (c *StoreCache) DeleteMany(ctx context.Context, keys []interface{}) error {
+	unlock := c.lockKeys(keys...)
+	defer unlock()
+
+	c.removeMany(keys)
+	if c.writeBehind {
+		for _, key := range keys {
+			c.enqueue(key, storeWrite{deleted: true})
+		}
+		return nil
+	}
+
+	return c.store.DeleteMany(ctx, keys)
+}
+
+// Put saves the value like Save, logging the error.
+func (c *StoreCache) Put(key, value interface{}) {
+	ctx := context.Background()
+	if err := c.Save(ctx, key, value); err != nil {
+		c.logErrorf(ctx, "cache %s: can't save key %v: %v", c.Name(), key, err)
+	}
+}
+
+// Remove deletes the key like Delete, logging the error.
+func (c *StoreCache) Remove(key interface{}) {
+	ctx := context.Background()
+	if err := c.Delete(ctx, key); err != nil {
+		c.logErrorf(ctx, "cache %s: can't delete key %v: %v", c.Name(), key, err)
+	}
+}
+
+// Pending returns the number of the queued writes.
+func (c *StoreCache) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.pending)
+}
+
+// Flush writes the queued writes to the store in batches, retrying the failed ones with the backoff.
+// The batches which still fail are queued again, unless their keys are written meanwhile, and their errors are returned.
+// Load finds the writes being flushed until Flush returns.
+func (c *StoreCache) Flush(ctx context.Context) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[interface{}]storeWrite)
+	c.flushing = pending
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.flushing = nil
+		c.mu.Unlock()
+	}()
+
+	var saves []KeyValue
+	var deletes []interface{}
+	for key, w := range pending {
+		if w.deleted {
+			deletes = append(deletes, key)
+		} else {
+			saves = append(saves, KeyValue{Key: key, Value: w.value})
+		}
+	}
+
+	var errs []error
+	for _, batch := range batches(len(saves), c.maxBatch) {
+		items := saves[batch[0]:batch[1]]
+		err := c.retry(ctx, func(ctx context.Context) error {
+			return c.store.SaveMany(ctx, items)
+		})
+		if err != nil {
+			errs = append(errs, err)
+			for _, item := range items {
+				c.requeue(item.Key, pending[item.Key])
+			}
+		}
+	}
+	for _, batch := range batches(len(deletes), c.maxBatch) {
+		keys := deletes[batch[0]:batch[1]]
+		err := c.retry(ctx, func(ctx context.Context) error {
+			return c.store.DeleteMany(ctx, keys)
+		})
+		if err != nil {
+			errs = append(errs, err)
+			for _, key := range keys {
+				c.requeue(key, pending[key])
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("can't flush cache %s: %w", c.Name(), errors.Join(errs...))
+	}
+	return nil
+}
+
+// Close stops the flush loop, flushes the queued writes and closes the cache if it is an io.Closer.
+// The cache must not be written after Close.
+func (c *StoreCache) Close() error {
+	select {
+	case <-c.close:
+		return nil
+	default:
+	}
+	close(c.close)
+	<-c.done
+
+	err := c.Flush(context.Background())
+	if closer, ok := c.NamedCache.(io.Closer); ok {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// enqueue queues the write of the key, replacing the previous one, and requests a flush of the full queue.
+func (c *StoreCache) enqueue(key interface{}, w storeWrite) {
+	c.mu.Lock()
+	c.pending[key] = w
+	full := c.maxBatch > 0 && len(c.pending) >= c.maxBatch
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// requeue queues the failed write of the key again, unless the key is written meanwhile.
+func (c *StoreCache) requeue(key interface{}, w storeWrite) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.pending[key]; !ok {
+		c.pending[key] = w
+	}
+}
+
+// queued returns the queued write of the key, or the one being flushed.
+func (c *StoreCache) queued(key interface{}) (storeWrite, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if w, ok := c.pending[key]; ok {
+		return w, true
+	}
+	w, ok := c.flushing[key]
+	return w, ok
+}
+
+// lockKeys locks the keys until unlock is called. A single key is locked alone, several keys are locked exclusively
+// of all the other keys, so the batches never deadlock.
+func (c *StoreCache) lockKeys(keys ...interface{}) (unlock func()) {
+	switch len(keys) {
+	case 0:
+		return func() {}
+	case 1:
+	default:
+		c.batchMu.Lock()
+		return c.batchMu.Unlock
+	}
+
+	key := keys[0]
+	c.batchMu.RLock()
+	c.mu.Lock()
+	l, ok := c.locks[key]
+	if !ok {
+		l = &keyLock{}
+		c.locks[key] = l
+	}
+	l.refs++
+	c.mu.Unlock()
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+		c.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(c.locks, key)
+		}
+		c.mu.Unlock()
+		c.batchMu.RUnlock()
+	}
+}
+
+// retry calls op until it succeeds or the attempts are over, doubling the backoff between the attempts.
+func (c *StoreCache) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil || attempt >= c.attempts {
+			return err
+		}
+
+		select {
+		case <-c.clock.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+		if c.maxBackoff > 0 && backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
+
+func (c *StoreCache) putMany(items []KeyValue) {
+	if putter, ok := c.NamedCache.(BatchPutter); ok {
+		putter.PutMany(items)
+		return
+	}
+	for _, item := range items {
+		c.NamedCache.Put(item.Key, item.Value)
+	}
+}
+
+func (c *StoreCache) removeMany(keys []interface{}) {
+	if putter, ok := c.NamedCache.(BatchPutter); ok {
+		putter.RemoveMany(keys)
+		return
+	}
+	for _, key := range keys {
+		c.NamedCache.Remove(key)
+	}
+}
+
+func (c *StoreCache) flushLoop() {
+	defer close(c.done)
+
+	for {
+		select {
+		case <-c.clock.After(c.flushInterval):
+		case <-c.kick:
+		case <-c.close:
+			return
+		}
+		ctx := context.Background()
+		if err := c.Flush(ctx); err != nil {
+			c.logErrorf(ctx, "%v", err)
+		}
+	}
+}
+
+// batches returns the [from, to) bounds of the batches of up to size of n items, size 0 means one batch.
+func batches(n, size int) [][2]int {
+	if n == 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = n
+	}
+	bounds := make([][2]int, 0, (n+size-1)/size)
+	for from := 0; from < n; from += size {
+		bounds = append(bounds, [2]int{from, min(from+size, n)})
+	}
+	return bounds
+}
@@ -0,0 +1,333 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore is an in-memory Store, which fails the first fails writes and calls onSave before the saves.
+type memStore struct {
+	mu     sync.Mutex
+	data   map[interface{}]interface{}
+	writes int
+	fails  int
+	onSave func()
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[interface{}]interface{})}
+}
+
+func (s *memStore) Load(_ context.Context, key interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.data[key]
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+	return v, nil
+}
+
+func (s *memStore) LoadMany(_ context.Context, keys []interface{}) (map[interface{}]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := make(map[interface{}]interface{})
+	for _, key := range keys {
+		if v, ok := s.data[key]; ok {
+			found[key] = v
+		}
+	}
+	return found, nil
+}
+
+func (s *memStore) Save(ctx context.Context, key, value interface{}) error {
+	return s.SaveMany(ctx, []cache.KeyValue{{Key: key, Value: value}})
+}
+
+func (s *memStore) SaveMany(_ context.Context, items []cache.KeyValue) error {
+	if s.onSave != nil {
+		s.onSave()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(); err != nil {
+		return err
+	}
+	for _, item := range items {
+		s.data[item.Key] = item.Value
+	}
+	return nil
+}
+
+func (s *memStore) Delete(ctx context.Context, key interface{}) error {
+	return s.DeleteMany(ctx, []interface{}{key})
+}
+
+func (s *memStore) DeleteMany(_ context.Context, keys []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		delete(s.data, key)
+	}
+	return nil
+}
+
+func (s *memStore) write() error {
+	s.writes++
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("store is unavailable")
+	}
+	return nil
+}
+
+func (s *memStore) get(key interface{}) (interface{}, bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.data[key]
+	return v, ok, s.writes
+}
+
+func TestStoreCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("write through", func(t *testing.T) {
+		t.Parallel()
+
+		store := newMemStore()
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewStoreCache(base, store)
+		require.NoError(t, err)
+
+		// act
+		errSave := c.Save(ctx, 1, "a")
+		store.fails = 1
+		errFailed := c.Save(ctx, 1, "b")
+		errDelete := c.Delete(ctx, 2)
+
+		// assert
+		assert.NoError(t, errSave)
+		assert.Error(t, errFailed)
+		assert.NoError(t, errDelete)
+		v, ok, _ := store.get(1)
+		assert.True(t, ok)
+		assert.Equal(t, "a", v)
+		v, ok = c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, "a", v)
+	})
+
+	t.Run("write through serializes saves of a key", func(t *testing.T) {
+		t.Parallel()
+
+		store := newMemStore()
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewStoreCache(base, store)
+		require.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			// act
+			var wg sync.WaitGroup
+			for _, value := range []string{"a", "b"} {
+				wg.Add(1)
+				go func(value string) {
+					defer wg.Done()
+					_ = c.Save(ctx, 1, value)
+				}(value)
+			}
+			wg.Wait()
+
+			// assert
+			stored, _, _ := store.get(1)
+			cached, _ := c.Get(1)
+			require.Equal(t, stored, cached)
+		}
+	})
+
+	t.Run("read through", func(t *testing.T) {
+		t.Parallel()
+
+		store := newMemStore()
+		store.data[1] = "a"
+		store.data[2] = "b"
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewStoreCache(base, store)
+		require.NoError(t, err)
+
+		// act
+		v, errLoad := c.Load(ctx, 1)
+		_, errMissing := c.Load(ctx, 3)
+		found, errMany := c.LoadMany(ctx, []interface{}{1, 2, 3})
+
+		// assert
+		require.NoError(t, errLoad)
+		assert.Equal(t, "a", v)
+		assert.ErrorIs(t, errMissing, cache.ErrNotFound)
+		require.NoError(t, errMany)
+		assert.Equal(t, map[interface{}]interface{}{1: "a", 2: "b"}, found)
+		assert.True(t, c.Contains(2))
+	})
+
+	t.Run("write behind coalesces writes", func(t *testing.T) {
+		t.Parallel()
+
+		store := newMemStore()
+		store.data[3] = "c"
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewStoreCache(base, store, cache.WithWriteBehind(time.Hour, 0))
+		require.NoError(t, err)
+
+		// act
+		for i := 0; i < 3; i++ {
+			c.Put(1, i)
+		}
+		c.Put(2, "b")
+		c.Remove(3)
+		_, errDeleted := c.Load(ctx, 3)
+		pending := c.Pending()
+		_, _, writesBefore := store.get(1)
+		errFlush := c.Flush(ctx)
+
+		// assert
+		assert.ErrorIs(t, errDeleted, cache.ErrNotFound)
+		assert.Equal(t, 3, pending)
+		assert.Equal(t, 0, writesBefore)
+		require.NoError(t, errFlush)
+		v, _, writes := store.get(1)
+		assert.Equal(t, 2, v)
+		assert.Equal(t, 2, writes)
+		_, ok, _ := store.get(3)
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Pending())
+		require.NoError(t, c.Close())
+	})
+
+	t.Run("write behind keeps flushing writes visible", func(t *testing.T) {
+		t.Parallel()
+
+		store := newMemStore()
+		store.data[1] = "old"
+		flushing := make(chan struct{})
+		release := make(chan struct{})
+		store.onSave = func() {
+			close(flushing)
+			<-release
+		}
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewStoreCache(base, store, cache.WithWriteBehind(time.Hour, 0))
+		require.NoError(t, err)
+		c.Put(1, "new")
+		base.Remove(1)
+
+		// act
+		flushed := make(chan error)
+		go func() {
+			flushed <- c.Flush(ctx)
+		}()
+		<-flushing
+		v, errLoad := c.Load(ctx, 1)
+		close(release)
+		errFlush := <-flushed
+
+		// assert
+		require.NoError(t, errLoad)
+		assert.Equal(t, "new", v)
+		require.NoError(t, errFlush)
+		stored, _, _ := store.get(1)
+		assert.Equal(t, "new", stored)
+		require.NoError(t, c.Close())
+	})
+
+	t.Run("write behind flushes by interval and full batch", func(t *testing.T) {
+		t.Parallel()
+
+		clock := cache.NewFakeClock(time.Now())
+		store := newMemStore()
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewStoreCache(base, store, cache.WithWriteBehind(time.Minute, 2), cache.WithStoreClock(clock))
+		require.NoError(t, err)
+
+		// act
+		c.Put(1, "a")
+		require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+		clock.Advance(time.Minute)
+
+		// assert
+		require.Eventually(t, func() bool { _, ok, _ := store.get(1); return ok }, time.Second, time.Millisecond)
+
+		// act
+		c.Put(2, "b")
+		c.Put(3, "c")
+
+		// assert
+		require.Eventually(t, func() bool { _, ok, _ := store.get(3); return ok }, time.Second, time.Millisecond)
+		require.NoError(t, c.Close())
+	})
+
+	t.Run("write behind retries and requeues", func(t *testing.T) {
+		t.Parallel()
+
+		store := newMemStore()
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		c, err := cache.NewStoreCache(base, store,
+			cache.WithWriteBehind(time.Hour, 0), cache.WithStoreRetry(2, time.Millisecond, 0))
+		require.NoError(t, err)
+		c.Put(1, "a")
+		store.fails = 3
+
+		// act
+		errFailed := c.Flush(ctx)
+		pending := c.Pending()
+		errClose := c.Close()
+
+		// assert
+		assert.Error(t, errFailed)
+		assert.Equal(t, 1, pending)
+		assert.NoError(t, errClose)
+		v, _, writes := store.get(1)
+		assert.Equal(t, "a", v)
+		assert.Equal(t, 4, writes)
+		assert.Equal(t, 0, c.Pending())
+	})
+
+	t.Run("wrong options", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+
+		// act
+		_, errInterval := cache.NewStoreCache(base, newMemStore(), cache.WithWriteBehind(0, 0))
+		_, errRetry := cache.NewStoreCache(base, newMemStore(), cache.WithStoreRetry(0, 0, 0))
+
+		// assert
+		assert.ErrorIs(t, errInterval, cache.ErrWrongFlushInterval)
+		assert.ErrorIs(t, errRetry, cache.ErrWrongRetry)
+	})
+}
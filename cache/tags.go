@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrWrongSweepInterval is the tag index sweep interval error if it is less than 0.
+var ErrWrongSweepInterval = errors.New("wrong sweep interval, it should be >= 0")
+
+const defaultSweepInterval = time.Minute
+
+// TagIndexOption is an option of the TagIndex.
+type TagIndexOption func(*TagIndex)
+
+// WithSweepInterval sets the interval of the background loop calling Sweep, which forgets the entries evicted
+// by the caches which can't report the evictions (see EvictCallback). The default is 1 minute,
+// 0 disables the loop if all the caches report their evictions.
+func WithSweepInterval(interval time.Duration) TagIndexOption {
+	return func(t *TagIndex) {
+		t.sweepInterval = interval
+	}
+}
+
+// WithTagIndexClock sets the clock of the sweep loop, e.g. a FakeClock for deterministic tests.
+// SystemClock is used by default.
+func WithTagIndexClock(clock Clock) TagIndexOption {
+	return func(t *TagIndex) {
+		t.clock = clock
+	}
+}
+
+// TagIndex is the index of the tagged entries of one or several caches: the entries put with PutWithTags
+// of the caches wrapped by Wrap are removed together by InvalidateTag, e.g. every cached response mentioning a product.
+// The caches are identified by name. The index forgets the removed entries, the entries evicted by the caches
+// are forgotten when the cache reports them (see EvictCallback) or on Sweep.
+// Always call Close after finishing using the index to stop the sweep loop.
+type TagIndex struct {
+	// invalidateMu is held exclusively by InvalidateTag and shared by PutWithTags,
+	// so an entry is never put after the invalidation of its tags has removed it from the index.
+	// The evict callbacks take only mu, so the caches can call them under their locks.
+	invalidateMu sync.RWMutex
+
+	mu     sync.Mutex
+	caches map[string]Cache
+	tags   map[string]map[tagRef]struct{}
+	refs   map[tagRef]*tagEntry
+
+	sweepInterval time.Duration
+	clock         Clock
+	close         chan struct{}
+}
+
+// tagRef is the reference to a key of a cache.
+type tagRef struct {
+	cache string
+	key   interface{}
+}
+
+// tagEntry is the tags of a key, it is replaced on every PutWithTags of the key.
+type tagEntry struct {
+	tags []string
+}
+
+// NewTagIndex creates an empty TagIndex.
+func NewTagIndex(opts ...TagIndexOption) (*TagIndex, error) {
+	t := &TagIndex{
+		caches: make(map[string]Cache),
+		tags:   make(map[string]map[tagRef]struct{}),
+		refs:   make(map[tagRef]*tagEntry),
+		clock:  SystemClock,
+		close:  make(chan struct{}),
+
+		sweepInterval: defaultSweepInterval,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.sweepInterval < 0 {
+		return nil, fmt.Errorf("can't create tag index: %w", ErrWrongSweepInterval)
+	}
+	if t.sweepInterval > 0 {
+		go t.sweepLoop()
+	}
+
+	return t, nil
+}
+
+// Wrap returns the cache c with the tag support backed by the index.
+func (t *TagIndex) Wrap(c NamedCache) *TaggedCache {
+	t.mu.Lock()
+	t.caches[c.Name()] = c
+	t.mu.Unlock()
+
+	return &TaggedCache{NamedCache: c, index: t}
+}
+
+// EvictCallback returns the evict callback for the cache with the name (e.g. for lfu.WithEvictCallback),
+// which forgets the evicted entries. The callback does not call the cache, so it is safe under its lock.
+func (t *TagIndex) EvictCallback(name string) func(key, value interface{}) {
+	return func(key, _ interface{}) {
+		t.forget(name, key)
+	}
+}
+
+// InvalidateTag removes the entries associated with the tag from all the caches, it returns the number of removed entries.
+func (t *TagIndex) InvalidateTag(tag string) int {
+	t.invalidateMu.Lock()
+	defer t.invalidateMu.Unlock()
+
+	t.mu.Lock()
+	refs := t.tags[tag]
+	removed := make([]tagRef, 0, len(refs))
+	caches := make([]Cache, 0, len(refs))
+	for ref := range refs {
+		t.deleteLocked(ref)
+		removed = append(removed, ref)
+		caches = append(caches, t.caches[ref.cache])
+	}
+	t.mu.Unlock()
+
+	// the caches are called without the lock, they may call the evict callbacks
+	for i, ref := range removed {
+		caches[i].Remove(ref.key)
+	}
+	return len(removed)
+}
+
+// Len returns the number of the tagged entries in the index.
+func (t *TagIndex) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.refs)
+}
+
+// Sweep forgets the entries which are no longer in their caches (see Cache.Contains).
+func (t *TagIndex) Sweep() {
+	t.mu.Lock()
+	refs := make([]tagRef, 0, len(t.refs))
+	entries := make([]*tagEntry, 0, len(t.refs))
+	caches := make([]Cache, 0, len(t.refs))
+	for ref, e := range t.refs {
+		refs = append(refs, ref)
+		entries = append(entries, e)
+		caches = append(caches, t.caches[ref.cache])
+	}
+	t.mu.Unlock()
+
+	var gone []int
+	for i, ref := range refs {
+		if !caches[i].Contains(ref.key) {
+			gone = append(gone, i)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, i := range gone {
+		// the key could be put again with new tags after the check
+		if t.refs[refs[i]] == entries[i] {
+			t.deleteLocked(refs[i])
+		}
+	}
+}
+
+// Close stops the sweep loop.
+func (t *TagIndex) Close() error {
+	select {
+	case <-t.close:
+	default:
+		close(t.close)
+	}
+
+	return nil
+}
+
+// set replaces the tags of the key.
+func (t *TagIndex) set(name string, key interface{}, tags []string) {
+	ref := tagRef{cache: name, key: key}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.deleteLocked(ref)
+	if len(tags) == 0 {
+		return
+	}
+	t.refs[ref] = &tagEntry{tags: append([]string(nil), tags...)}
+	for _, tag := range tags {
+		refs, ok := t.tags[tag]
+		if !ok {
+			refs = make(map[tagRef]struct{})
+			t.tags[tag] = refs
+		}
+		refs[ref] = struct{}{}
+	}
+}
+
+func (t *TagIndex) forget(name string, key interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.deleteLocked(tagRef{cache: name, key: key})
+}
+
+func (t *TagIndex) forgetCache(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for ref := range t.refs {
+		if ref.cache == name {
+			t.deleteLocked(ref)
+		}
+	}
+}
+
+// deleteLocked removes the key from the index, t.mu must be held.
+func (t *TagIndex) deleteLocked(ref tagRef) {
+	e, ok := t.refs[ref]
+	if !ok {
+		return
+	}
+	delete(t.refs, ref)
+	for _, tag := range e.tags {
+		refs := t.tags[tag]
+		delete(refs, ref)
+		if len(refs) == 0 {
+			delete(t.tags, tag)
+		}
+	}
+}
+
+func (t *TagIndex) sweepLoop() {
+	for {
+		select {
+		case <-t.clock.After(t.sweepInterval):
+		case <-t.close:
+			return
+		}
+		t.Sweep()
+	}
+}
+
+var (
+	_ NamedCache     = &TaggedCache{}
+	_ TagPutter      = &TaggedCache{}
+	_ TagInvalidator = &TaggedCache{}
+	_ TagInvalidator = &TagIndex{}
+)
+
+// TaggedCache is a cache with the tag support (see TagIndex.Wrap).
+type TaggedCache struct {
+	NamedCache
+	index *TagIndex
+}
+
+// PutWithTags puts the value into the cache and associates it with the tags, replacing the previous tags of the key.
+// Put keeps the tags of the key. The put and the indexing are atomic for InvalidateTag.
+func (c *TaggedCache) PutWithTags(key, value interface{}, tags ...string) {
+	c.index.invalidateMu.RLock()
+	defer c.index.invalidateMu.RUnlock()
+
+	c.index.set(c.Name(), key, tags)
+	c.NamedCache.Put(key, value)
+}
+
+// InvalidateTag removes the entries associated with the tag from all the caches of the index,
+// it returns the number of removed entries.
+func (c *TaggedCache) InvalidateTag(tag string) int {
+	return c.index.InvalidateTag(tag)
+}
+
+// Remove removes the value by key from the cache and the index.
+// The index forgets the key first, so a concurrent PutWithTags leaves at most a stale index entry, not an untagged value.
+func (c *TaggedCache) Remove(key interface{}) {
+	c.index.forget(c.Name(), key)
+	c.NamedCache.Remove(key)
+}
+
+// Clear clears the cache and forgets its entries in the index (see Remove).
+func (c *TaggedCache) Clear() {
+	c.index.forgetCache(c.Name())
+	c.NamedCache.Clear()
+}
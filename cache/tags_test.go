@@ -0,0 +1,195 @@
+package cache_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/arc"
+	"github.com/catalystgo/cache-go/cache/lru"
+	"github.com/catalystgo/cache-go/cache/sieve"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagIndex(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalidate across caches", func(t *testing.T) {
+		t.Parallel()
+
+		index, err := cache.NewTagIndex()
+		require.NoError(t, err)
+		defer index.Close()
+		lruCache, err := lru.NewCache("products", 10, 0)
+		require.NoError(t, err)
+		arcCache, err := arc.NewCache("responses", 10, 0)
+		require.NoError(t, err)
+		products := index.Wrap(lruCache)
+		responses := index.Wrap(arcCache)
+
+		products.PutWithTags(1, "product 1", "product:1")
+		products.PutWithTags(2, "product 2", "product:2")
+		responses.PutWithTags("list", "products 1 and 2", "product:1", "product:2")
+		responses.PutWithTags("one", "product 2", "product:2")
+
+		// act
+		removed := index.InvalidateTag("product:1")
+
+		// assert
+		assert.Equal(t, 2, removed)
+		assert.False(t, products.Contains(1))
+		assert.False(t, responses.Contains("list"))
+		assert.True(t, products.Contains(2))
+		assert.True(t, responses.Contains("one"))
+		assert.Equal(t, 2, index.Len())
+	})
+
+	t.Run("put replaces tags", func(t *testing.T) {
+		t.Parallel()
+
+		index, err := cache.NewTagIndex()
+		require.NoError(t, err)
+		defer index.Close()
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		tagged := index.Wrap(c)
+		tagged.PutWithTags(1, "old", "a")
+		tagged.PutWithTags(1, "new", "b")
+
+		// act
+		removedA := tagged.InvalidateTag("a")
+		removedB := tagged.InvalidateTag("b")
+
+		// assert
+		assert.Equal(t, 0, removedA)
+		assert.Equal(t, 1, removedB)
+		assert.False(t, tagged.Contains(1))
+	})
+
+	t.Run("remove and clear forget entries", func(t *testing.T) {
+		t.Parallel()
+
+		index, err := cache.NewTagIndex()
+		require.NoError(t, err)
+		defer index.Close()
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		tagged := index.Wrap(c)
+		tagged.PutWithTags(1, 1, "a")
+		tagged.PutWithTags(2, 2, "a")
+		tagged.PutWithTags(3, 3, "a")
+
+		// act
+		tagged.Remove(1)
+		lenAfterRemove := index.Len()
+		tagged.Clear()
+
+		// assert
+		assert.Equal(t, 2, lenAfterRemove)
+		assert.Equal(t, 0, index.Len())
+	})
+
+	t.Run("invalidate waits for put", func(t *testing.T) {
+		t.Parallel()
+
+		index, err := cache.NewTagIndex()
+		require.NoError(t, err)
+		defer index.Close()
+		c, err := lru.NewCache("test", 10, 0)
+		require.NoError(t, err)
+		blocking := &blockingPutCache{NamedCache: c, started: make(chan struct{}), release: make(chan struct{})}
+		tagged := index.Wrap(blocking)
+		putDone := make(chan struct{})
+		go func() {
+			defer close(putDone)
+			tagged.PutWithTags(1, 1, "a")
+		}()
+		<-blocking.started
+
+		// act
+		var invalidated atomic.Bool
+		removed := make(chan int, 1)
+		go func() {
+			removed <- index.InvalidateTag("a")
+			invalidated.Store(true)
+		}()
+		// the invalidation waits for the put
+		assert.Never(t, invalidated.Load, 20*time.Millisecond, time.Millisecond)
+		close(blocking.release)
+		<-putDone
+
+		// assert
+		assert.Equal(t, 1, <-removed)
+		assert.False(t, tagged.Contains(1))
+		assert.Equal(t, 0, index.Len())
+	})
+
+	t.Run("evict callback", func(t *testing.T) {
+		t.Parallel()
+
+		index, err := cache.NewTagIndex()
+		require.NoError(t, err)
+		defer index.Close()
+		c, err := sieve.NewCache("test", 1, 0, sieve.WithEvictCallback(index.EvictCallback("test")))
+		require.NoError(t, err)
+		tagged := index.Wrap(c)
+
+		// act
+		tagged.PutWithTags(1, 1, "a")
+		tagged.PutWithTags(2, 2, "a")
+
+		// assert
+		assert.Equal(t, 1, index.Len())
+		assert.Equal(t, 1, tagged.InvalidateTag("a"))
+		assert.Equal(t, 0, tagged.Len())
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		t.Parallel()
+
+		clock := cache.NewFakeClock(time.Now())
+		index, err := cache.NewTagIndex(cache.WithSweepInterval(time.Minute), cache.WithTagIndexClock(clock))
+		require.NoError(t, err)
+		defer index.Close()
+		c, err := arc.NewCache("test", 1, 0)
+		require.NoError(t, err)
+		tagged := index.Wrap(c)
+		tagged.PutWithTags(1, 1, "a")
+		tagged.PutWithTags(2, 2, "a")
+		require.Eventually(t, func() bool { return clock.Waiters() >= 1 }, time.Second, time.Millisecond)
+
+		// act
+		clock.Advance(time.Minute)
+
+		// assert
+		assert.Eventually(t, func() bool { return index.Len() == 1 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("wrong sweep interval", func(t *testing.T) {
+		t.Parallel()
+
+		// act
+		_, err := cache.NewTagIndex(cache.WithSweepInterval(-time.Second))
+
+		// assert
+		require.ErrorIs(t, err, cache.ErrWrongSweepInterval)
+	})
+}
+
+// blockingPutCache signals started on the first Put and waits for release before putting, the other puts don't wait.
+type blockingPutCache struct {
+	cache.NamedCache
+	started chan struct{}
+	release chan struct{}
+	blocked atomic.Bool
+}
+
+func (c *blockingPutCache) Put(key, value interface{}) {
+	if c.blocked.CompareAndSwap(false, true) {
+		close(c.started)
+		<-c.release
+	}
+	c.NamedCache.Put(key, value)
+}
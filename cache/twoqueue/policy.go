@@ -0,0 +1,210 @@
+package twoqueue
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// policy is the 2Q replacement policy over the simplelru lists, like lru.TwoQueueCache: the new keys go to the recent list,
+// the keys used again are promoted to the frequent list, and the ghost list keeps the keys recently evicted
+// from the recent list, which go straight to the frequent list when they are put again.
+// Unlike lru.TwoQueueCache it reports the evictions to onEvict and can evict on demand (see removeOldest),
+// so the cache keeps the total cost without scanning the keys.
+type policy struct {
+	mu          sync.Mutex
+	size        int
+	recentSize  int
+	recent      *simplelru.LRU
+	frequent    *simplelru.LRU
+	recentEvict *simplelru.LRU
+	// onEvict is called under mu for every entry evicted by the policy, but not for the removed ones
+	onEvict simplelru.EvictCallback
+}
+
+func newPolicy(size int, recentRatio, ghostRatio float64, onEvict simplelru.EvictCallback) (*policy, error) {
+	if size <= 0 {
+		return nil, errors.New("invalid size")
+	}
+	if recentRatio < 0.0 || recentRatio > 1.0 {
+		return nil, errors.New("invalid recent ratio")
+	}
+	if ghostRatio < 0.0 || ghostRatio > 1.0 {
+		return nil, errors.New("invalid ghost ratio")
+	}
+
+	recent, err := simplelru.NewLRU(size, nil)
+	if err != nil {
+		return nil, err
+	}
+	frequent, err := simplelru.NewLRU(size, nil)
+	if err != nil {
+		return nil, err
+	}
+	recentEvict, err := simplelru.NewLRU(int(float64(size)*ghostRatio), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy{
+		size:        size,
+		recentSize:  int(float64(size) * recentRatio),
+		recent:      recent,
+		frequent:    frequent,
+		recentEvict: recentEvict,
+		onEvict:     onEvict,
+	}, nil
+}
+
+// Get looks up the value of the key, a key of the recent list is promoted to the frequent one.
+func (p *policy) Get(key interface{}) (value interface{}, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if value, ok = p.frequent.Get(key); ok {
+		return value, true
+	}
+	if value, ok = p.recent.Peek(key); ok {
+		p.recent.Remove(key)
+		p.frequent.Add(key, value)
+		return value, true
+	}
+	return nil, false
+}
+
+// Add adds the value of the key, evicting an entry if the cache is full.
+func (p *policy) Add(key, value interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.frequent.Contains(key) {
+		p.frequent.Add(key, value)
+		return
+	}
+	if p.recent.Contains(key) {
+		p.recent.Remove(key)
+		p.frequent.Add(key, value)
+		return
+	}
+	if p.recentEvict.Contains(key) {
+		p.ensureSpace(true)
+		p.recentEvict.Remove(key)
+		p.frequent.Add(key, value)
+		return
+	}
+
+	p.ensureSpace(false)
+	p.recent.Add(key, value)
+}
+
+// ensureSpace evicts an entry if the cache is full: from the recent list if it is over its target size, otherwise
+// from the frequent list. recentEvict is set when the key being added is in the ghost list.
+func (p *policy) ensureSpace(recentEvict bool) {
+	recentLen := p.recent.Len()
+	if recentLen+p.frequent.Len() < p.size {
+		return
+	}
+
+	if recentLen > 0 && (recentLen > p.recentSize || (recentLen == p.recentSize && !recentEvict)) {
+		if !p.evictRecent(nil) {
+			p.evictFrequent(nil)
+		}
+		return
+	}
+	if !p.evictFrequent(nil) {
+		p.evictRecent(nil)
+	}
+}
+
+// removeOldest evicts the oldest entry of the recent list, or of the frequent one if the recent list is empty,
+// so the entries used once go before the frequently used ones. The key except is not evicted.
+// It reports whether an entry was evicted.
+func (p *policy) removeOldest(except interface{}) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.evictRecent(except) || p.evictFrequent(except)
+}
+
+// evictRecent evicts the oldest entry of the recent list into the ghost list, unless the list is empty or it is except.
+func (p *policy) evictRecent(except interface{}) bool {
+	key, value, ok := p.recent.GetOldest()
+	if !ok || (except != nil && key == except) {
+		return false
+	}
+	p.recent.RemoveOldest()
+	p.recentEvict.Add(key, nil)
+	p.onEvict(key, value)
+	return true
+}
+
+// evictFrequent evicts the oldest entry of the frequent list, unless the list is empty or it is except.
+func (p *policy) evictFrequent(except interface{}) bool {
+	key, value, ok := p.frequent.GetOldest()
+	if !ok || (except != nil && key == except) {
+		return false
+	}
+	p.frequent.RemoveOldest()
+	p.onEvict(key, value)
+	return true
+}
+
+// Peek returns the value of the key without updating the recency or the frequency.
+func (p *policy) Peek(key interface{}) (value interface{}, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if value, ok = p.frequent.Peek(key); ok {
+		return value, true
+	}
+	return p.recent.Peek(key)
+}
+
+// Contains checks for the presence of the key without updating the recency or the frequency.
+func (p *policy) Contains(key interface{}) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.frequent.Contains(key) || p.recent.Contains(key)
+}
+
+// Remove removes the key, it is not reported as evicted.
+func (p *policy) Remove(key interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.frequent.Remove(key) {
+		return
+	}
+	if p.recent.Remove(key) {
+		return
+	}
+	p.recentEvict.Remove(key)
+}
+
+// Purge removes all the keys, they are not reported as evicted.
+func (p *policy) Purge() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recent.Purge()
+	p.frequent.Purge()
+	p.recentEvict.Purge()
+}
+
+// Keys returns the keys, the frequently used ones first, from the oldest to the newest in each list.
+func (p *policy) Keys() []interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append(p.frequent.Keys(), p.recent.Keys()...)
+}
+
+// Len returns the number of the entries.
+func (p *policy) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.recent.Len() + p.frequent.Len()
+}
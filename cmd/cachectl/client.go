@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/catalystgo/cache-go/cache/admin"
+)
+
+// client is an HTTP client for the admin API (see package cache/admin).
+type client struct {
+	http *http.Client
+	addr string
+}
+
+func newClient(httpClient *http.Client, addr string) *client {
+	return &client{
+		http: httpClient,
+		addr: strings.TrimRight(addr, "/"),
+	}
+}
+
+func (c *client) List(ctx context.Context) ([]admin.Stats, error) {
+	var resp []admin.Stats
+	err := c.do(ctx, http.MethodGet, "/caches", nil, &resp)
+	return resp, err
+}
+
+func (c *client) Stats(ctx context.Context, name string) (admin.Stats, error) {
+	var resp admin.Stats
+	err := c.do(ctx, http.MethodGet, "/caches/stats", url.Values{"name": {name}}, &resp)
+	return resp, err
+}
+
+func (c *client) Keys(ctx context.Context, name string, limit int) (admin.Keys, error) {
+	q := url.Values{"name": {name}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var resp admin.Keys
+	err := c.do(ctx, http.MethodGet, "/caches/keys", q, &resp)
+	return resp, err
+}
+
+func (c *client) Purge(ctx context.Context, name, prefix string) (admin.Purge, error) {
+	q := url.Values{"name": {name}}
+	if prefix != "" {
+		q.Set("prefix", prefix)
+	}
+	var resp admin.Purge
+	err := c.do(ctx, http.MethodPost, "/caches/purge", q, &resp)
+	return resp, err
+}
+
+func (c *client) Resize(ctx context.Context, name string, capacity int) (admin.Resize, error) {
+	q := url.Values{"name": {name}, "cap": {strconv.Itoa(capacity)}}
+	var resp admin.Resize
+	err := c.do(ctx, http.MethodPost, "/caches/resize", q, &resp)
+	return resp, err
+}
+
+func (c *client) do(ctx context.Context, method, path string, query url.Values, resp interface{}) error {
+	u := c.addr + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("can't create request: %w", err)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e admin.Error
+		if err = json.NewDecoder(res.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("%s %s: unexpected status %s", method, path, res.Status)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, e.Error)
+	}
+
+	if err = json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return fmt.Errorf("%s %s: can't decode response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Command cachectl operates the caches of a running service through its admin HTTP endpoint
+// (see package cache/admin).
+//
+// Usage:
+//
+//	cachectl [flags] list
+//	cachectl [flags] stats <name>
+//	cachectl [flags] keys [-limit N] <name>
+//	cachectl [flags] purge [-prefix P] <name>
+//	cachectl [flags] resize <name> <cap>
+//
+// Flags:
+//
+//	-addr     base URL of the admin endpoint (default $CACHECTL_ADDR or http://localhost:8080/debug/cache)
+//	-o        output format: table or json (default table)
+//	-timeout  request timeout (default 5s)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultAddr = "http://localhost:8080/debug/cache"
+
+var errUsage = errors.New("usage: cachectl [-addr URL] [-o table|json] [-timeout D] list|stats|keys|purge|resize [args]")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "cachectl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	addr := os.Getenv("CACHECTL_ADDR")
+	if addr == "" {
+		addr = defaultAddr
+	}
+
+	fs := flag.NewFlagSet("cachectl", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&addr, "addr", addr, "base URL of the admin endpoint")
+	format := fs.String("o", formatTable, "output format: table or json")
+	timeout := fs.Duration("timeout", 5*time.Second, "request timeout")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	if *format != formatTable && *format != formatJSON {
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return errUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	c := newClient(http.DefaultClient, addr)
+	p := printer{w: stdout, format: *format}
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "list":
+		stats, err := c.List(ctx)
+		if err != nil {
+			return err
+		}
+		return p.stats(stats...)
+	case "stats":
+		name, err := oneName(cmd, cmdArgs)
+		if err != nil {
+			return err
+		}
+		stats, err := c.Stats(ctx, name)
+		if err != nil {
+			return err
+		}
+		return p.stats(stats)
+	case "keys":
+		cfs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+		cfs.SetOutput(io.Discard)
+		limit := cfs.Int("limit", 0, "max number of keys to print, 0 means all")
+		if err := cfs.Parse(cmdArgs); err != nil {
+			return fmt.Errorf("%w: %s", errUsage, err)
+		}
+		name, err := oneName(cmd, cfs.Args())
+		if err != nil {
+			return err
+		}
+		keys, err := c.Keys(ctx, name, *limit)
+		if err != nil {
+			return err
+		}
+		return p.keys(keys)
+	case "purge":
+		cfs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+		cfs.SetOutput(io.Discard)
+		prefix := cfs.String("prefix", "", "remove only the string keys with this prefix")
+		if err := cfs.Parse(cmdArgs); err != nil {
+			return fmt.Errorf("%w: %s", errUsage, err)
+		}
+		name, err := oneName(cmd, cfs.Args())
+		if err != nil {
+			return err
+		}
+		purge, err := c.Purge(ctx, name, *prefix)
+		if err != nil {
+			return err
+		}
+		return p.purge(purge)
+	case "resize":
+		if len(cmdArgs) != 2 {
+			return fmt.Errorf("%w: resize expects <name> <cap>", errUsage)
+		}
+		capacity, err := strconv.Atoi(cmdArgs[1])
+		if err != nil {
+			return fmt.Errorf("wrong cap %q: %w", cmdArgs[1], err)
+		}
+		resize, err := c.Resize(ctx, cmdArgs[0], capacity)
+		if err != nil {
+			return err
+		}
+		return p.resize(resize)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+}
+
+func oneName(cmd string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%w: %s expects <name>", errUsage, cmd)
+	}
+	return args[0], nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/catalystgo/cache-go/cache"
+	"github.com/catalystgo/cache-go/cache/admin"
+	"github.com/catalystgo/cache-go/cache/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	c, err := lru.NewCache("/Service/Method", 10, 0)
+	require.NoError(t, err)
+	defer c.Close()
+
+	r := cache.NewRegistry()
+	require.NoError(t, r.Register(c))
+
+	srv := httptest.NewServer(admin.NewHandler(r))
+	defer srv.Close()
+
+	c.Put("user:1", 1)
+	c.Put("user:2", 2)
+	c.Put("order:1", 3)
+
+	t.Run("list as table", func(t *testing.T) {
+		var out bytes.Buffer
+
+		// act
+		err := run([]string{"-addr", srv.URL, "list"}, &out)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "NAME             TYPE        LEN  CAP\n/Service/Method  *lru.Cache  3    10\n", out.String())
+	})
+
+	t.Run("keys as json", func(t *testing.T) {
+		var out bytes.Buffer
+
+		// act
+		err := run([]string{"-addr", srv.URL, "-o", "json", "keys", "-limit", "1", "/Service/Method"}, &out)
+
+		// assert
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name":"/Service/Method","total":3,"keys":["user:1"]}`, out.String())
+	})
+
+	t.Run("purge by prefix", func(t *testing.T) {
+		var out bytes.Buffer
+
+		// act
+		err := run([]string{"-addr", srv.URL, "-o", "json", "purge", "-prefix", "user:", "/Service/Method"}, &out)
+
+		// assert
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name":"/Service/Method","prefix":"user:","removed":2}`, out.String())
+		require.Equal(t, 1, c.Len())
+	})
+
+	t.Run("unknown cache", func(t *testing.T) {
+		var out bytes.Buffer
+
+		// act
+		err := run([]string{"-addr", srv.URL, "stats", "unknown"}, &out)
+
+		// assert
+		require.ErrorContains(t, err, admin.ErrNotFound.Error())
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		var out bytes.Buffer
+
+		// act
+		err := run([]string{"-addr", srv.URL, "drop"}, &out)
+
+		// assert
+		require.ErrorIs(t, err, errUsage)
+	})
+}
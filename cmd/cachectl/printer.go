@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/catalystgo/cache-go/cache/admin"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// printer writes admin API responses either as an aligned table or as JSON.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func (p printer) stats(stats ...admin.Stats) error {
+	if p.format == formatJSON {
+		if len(stats) == 1 {
+			return p.json(stats[0])
+		}
+		return p.json(stats)
+	}
+	return p.table(func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tTYPE\tLEN\tCAP")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", s.Name, s.Type, s.Len, s.Cap)
+		}
+	})
+}
+
+func (p printer) keys(keys admin.Keys) error {
+	if p.format == formatJSON {
+		return p.json(keys)
+	}
+	return p.table(func(tw io.Writer) {
+		fmt.Fprintln(tw, "KEY")
+		for _, k := range keys.Keys {
+			fmt.Fprintln(tw, k)
+		}
+		if len(keys.Keys) < keys.Total {
+			fmt.Fprintf(tw, "... %d of %d keys shown\n", len(keys.Keys), keys.Total)
+		}
+	})
+}
+
+func (p printer) purge(purge admin.Purge) error {
+	if p.format == formatJSON {
+		return p.json(purge)
+	}
+	return p.table(func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tPREFIX\tREMOVED")
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", purge.Name, purge.Prefix, purge.Removed)
+	})
+}
+
+func (p printer) resize(resize admin.Resize) error {
+	if p.format == formatJSON {
+		return p.json(resize)
+	}
+	return p.table(func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tOLD CAP\tNEW CAP")
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", resize.Name, resize.OldCap, resize.NewCap)
+	})
+}
+
+func (p printer) table(write func(tw io.Writer)) error {
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	write(tw)
+	return tw.Flush()
+}
+
+func (p printer) json(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -12,6 +12,7 @@ require (
 require (
 	github.com/catalystgo/tracerok v0.0.1
 	github.com/golang/mock v1.6.0
+	google.golang.org/protobuf v1.32.0
 )
 
 require (
@@ -32,7 +33,6 @@ require (
 	golang.org/x/sys v0.16.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto v0.0.0-20221118155620-16455021b5e6 // indirect
-	google.golang.org/protobuf v1.32.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	cache "github.com/catalystgo/cache-go/cache"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -467,3 +468,761 @@ func (mr *MockKeysGetterMockRecorder) Keys() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockKeysGetter)(nil).Keys))
 }
+
+// MockCostPutter is a mock of CostPutter interface.
+type MockCostPutter struct {
+	ctrl     *gomock.Controller
+	recorder *MockCostPutterMockRecorder
+}
+
+// MockCostPutterMockRecorder is the mock recorder for MockCostPutter.
+type MockCostPutterMockRecorder struct {
+	mock *MockCostPutter
+}
+
+// NewMockCostPutter creates a new mock instance.
+func NewMockCostPutter(ctrl *gomock.Controller) *MockCostPutter {
+	mock := &MockCostPutter{ctrl: ctrl}
+	mock.recorder = &MockCostPutterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCostPutter) EXPECT() *MockCostPutterMockRecorder {
+	return m.recorder
+}
+
+// PutWithCost mocks base method.
+func (m *MockCostPutter) PutWithCost(key, value interface{}, cost int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PutWithCost", key, value, cost)
+}
+
+// PutWithCost indicates an expected call of PutWithCost.
+func (mr *MockCostPutterMockRecorder) PutWithCost(key, value, cost interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWithCost", reflect.TypeOf((*MockCostPutter)(nil).PutWithCost), key, value, cost)
+}
+
+// MockSoftTTLPutter is a mock of SoftTTLPutter interface.
+type MockSoftTTLPutter struct {
+	ctrl     *gomock.Controller
+	recorder *MockSoftTTLPutterMockRecorder
+}
+
+// MockSoftTTLPutterMockRecorder is the mock recorder for MockSoftTTLPutter.
+type MockSoftTTLPutterMockRecorder struct {
+	mock *MockSoftTTLPutter
+}
+
+// NewMockSoftTTLPutter creates a new mock instance.
+func NewMockSoftTTLPutter(ctrl *gomock.Controller) *MockSoftTTLPutter {
+	mock := &MockSoftTTLPutter{ctrl: ctrl}
+	mock.recorder = &MockSoftTTLPutterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSoftTTLPutter) EXPECT() *MockSoftTTLPutterMockRecorder {
+	return m.recorder
+}
+
+// PutWithSoftTTL mocks base method.
+func (m *MockSoftTTLPutter) PutWithSoftTTL(key, value interface{}, softTTL, hardTTL time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PutWithSoftTTL", key, value, softTTL, hardTTL)
+}
+
+// PutWithSoftTTL indicates an expected call of PutWithSoftTTL.
+func (mr *MockSoftTTLPutterMockRecorder) PutWithSoftTTL(key, value, softTTL, hardTTL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWithSoftTTL", reflect.TypeOf((*MockSoftTTLPutter)(nil).PutWithSoftTTL), key, value, softTTL, hardTTL)
+}
+
+// MockStaleGetter is a mock of StaleGetter interface.
+type MockStaleGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockStaleGetterMockRecorder
+}
+
+// MockStaleGetterMockRecorder is the mock recorder for MockStaleGetter.
+type MockStaleGetterMockRecorder struct {
+	mock *MockStaleGetter
+}
+
+// NewMockStaleGetter creates a new mock instance.
+func NewMockStaleGetter(ctrl *gomock.Controller) *MockStaleGetter {
+	mock := &MockStaleGetter{ctrl: ctrl}
+	mock.recorder = &MockStaleGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStaleGetter) EXPECT() *MockStaleGetterMockRecorder {
+	return m.recorder
+}
+
+// GetStale mocks base method.
+func (m *MockStaleGetter) GetStale(key interface{}) (interface{}, bool, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStale", key)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(bool)
+	return ret0, ret1, ret2
+}
+
+// GetStale indicates an expected call of GetStale.
+func (mr *MockStaleGetterMockRecorder) GetStale(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStale", reflect.TypeOf((*MockStaleGetter)(nil).GetStale), key)
+}
+
+// MockTTLGetter is a mock of TTLGetter interface.
+type MockTTLGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockTTLGetterMockRecorder
+}
+
+// MockTTLGetterMockRecorder is the mock recorder for MockTTLGetter.
+type MockTTLGetterMockRecorder struct {
+	mock *MockTTLGetter
+}
+
+// NewMockTTLGetter creates a new mock instance.
+func NewMockTTLGetter(ctrl *gomock.Controller) *MockTTLGetter {
+	mock := &MockTTLGetter{ctrl: ctrl}
+	mock.recorder = &MockTTLGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTTLGetter) EXPECT() *MockTTLGetterMockRecorder {
+	return m.recorder
+}
+
+// TTL mocks base method.
+func (m *MockTTLGetter) TTL() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TTL")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// TTL indicates an expected call of TTL.
+func (mr *MockTTLGetterMockRecorder) TTL() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TTL", reflect.TypeOf((*MockTTLGetter)(nil).TTL))
+}
+
+// MockComputeTimePutter is a mock of ComputeTimePutter interface.
+type MockComputeTimePutter struct {
+	ctrl     *gomock.Controller
+	recorder *MockComputeTimePutterMockRecorder
+}
+
+// MockComputeTimePutterMockRecorder is the mock recorder for MockComputeTimePutter.
+type MockComputeTimePutterMockRecorder struct {
+	mock *MockComputeTimePutter
+}
+
+// NewMockComputeTimePutter creates a new mock instance.
+func NewMockComputeTimePutter(ctrl *gomock.Controller) *MockComputeTimePutter {
+	mock := &MockComputeTimePutter{ctrl: ctrl}
+	mock.recorder = &MockComputeTimePutterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockComputeTimePutter) EXPECT() *MockComputeTimePutterMockRecorder {
+	return m.recorder
+}
+
+// PutWithComputeTime mocks base method.
+func (m *MockComputeTimePutter) PutWithComputeTime(key, value interface{}, ttl, computeTime time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PutWithComputeTime", key, value, ttl, computeTime)
+}
+
+// PutWithComputeTime indicates an expected call of PutWithComputeTime.
+func (mr *MockComputeTimePutterMockRecorder) PutWithComputeTime(key, value, ttl, computeTime interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWithComputeTime", reflect.TypeOf((*MockComputeTimePutter)(nil).PutWithComputeTime), key, value, ttl, computeTime)
+}
+
+// MockComputeTimeGetter is a mock of ComputeTimeGetter interface.
+type MockComputeTimeGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockComputeTimeGetterMockRecorder
+}
+
+// MockComputeTimeGetterMockRecorder is the mock recorder for MockComputeTimeGetter.
+type MockComputeTimeGetterMockRecorder struct {
+	mock *MockComputeTimeGetter
+}
+
+// NewMockComputeTimeGetter creates a new mock instance.
+func NewMockComputeTimeGetter(ctrl *gomock.Controller) *MockComputeTimeGetter {
+	mock := &MockComputeTimeGetter{ctrl: ctrl}
+	mock.recorder = &MockComputeTimeGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockComputeTimeGetter) EXPECT() *MockComputeTimeGetterMockRecorder {
+	return m.recorder
+}
+
+// GetWithComputeTime mocks base method.
+func (m *MockComputeTimeGetter) GetWithComputeTime(key interface{}) (interface{}, time.Time, time.Duration, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithComputeTime", key)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(time.Time)
+	ret2, _ := ret[2].(time.Duration)
+	ret3, _ := ret[3].(bool)
+	return ret0, ret1, ret2, ret3
+}
+
+// GetWithComputeTime indicates an expected call of GetWithComputeTime.
+func (mr *MockComputeTimeGetterMockRecorder) GetWithComputeTime(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithComputeTime", reflect.TypeOf((*MockComputeTimeGetter)(nil).GetWithComputeTime), key)
+}
+
+// MockSlidingTTLPutter is a mock of SlidingTTLPutter interface.
+type MockSlidingTTLPutter struct {
+	ctrl     *gomock.Controller
+	recorder *MockSlidingTTLPutterMockRecorder
+}
+
+// MockSlidingTTLPutterMockRecorder is the mock recorder for MockSlidingTTLPutter.
+type MockSlidingTTLPutterMockRecorder struct {
+	mock *MockSlidingTTLPutter
+}
+
+// NewMockSlidingTTLPutter creates a new mock instance.
+func NewMockSlidingTTLPutter(ctrl *gomock.Controller) *MockSlidingTTLPutter {
+	mock := &MockSlidingTTLPutter{ctrl: ctrl}
+	mock.recorder = &MockSlidingTTLPutterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSlidingTTLPutter) EXPECT() *MockSlidingTTLPutterMockRecorder {
+	return m.recorder
+}
+
+// PutWithFixedTTL mocks base method.
+func (m *MockSlidingTTLPutter) PutWithFixedTTL(key, value interface{}, ttl time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PutWithFixedTTL", key, value, ttl)
+}
+
+// PutWithFixedTTL indicates an expected call of PutWithFixedTTL.
+func (mr *MockSlidingTTLPutterMockRecorder) PutWithFixedTTL(key, value, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWithFixedTTL", reflect.TypeOf((*MockSlidingTTLPutter)(nil).PutWithFixedTTL), key, value, ttl)
+}
+
+// PutWithSlidingTTL mocks base method.
+func (m *MockSlidingTTLPutter) PutWithSlidingTTL(key, value interface{}, ttl time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PutWithSlidingTTL", key, value, ttl)
+}
+
+// PutWithSlidingTTL indicates an expected call of PutWithSlidingTTL.
+func (mr *MockSlidingTTLPutterMockRecorder) PutWithSlidingTTL(key, value, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWithSlidingTTL", reflect.TypeOf((*MockSlidingTTLPutter)(nil).PutWithSlidingTTL), key, value, ttl)
+}
+
+// MockMetaGetter is a mock of MetaGetter interface.
+type MockMetaGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockMetaGetterMockRecorder
+}
+
+// MockMetaGetterMockRecorder is the mock recorder for MockMetaGetter.
+type MockMetaGetterMockRecorder struct {
+	mock *MockMetaGetter
+}
+
+// NewMockMetaGetter creates a new mock instance.
+func NewMockMetaGetter(ctrl *gomock.Controller) *MockMetaGetter {
+	mock := &MockMetaGetter{ctrl: ctrl}
+	mock.recorder = &MockMetaGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMetaGetter) EXPECT() *MockMetaGetterMockRecorder {
+	return m.recorder
+}
+
+// GetWithMeta mocks base method.
+func (m *MockMetaGetter) GetWithMeta(key interface{}) (cache.EntryMeta, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithMeta", key)
+	ret0, _ := ret[0].(cache.EntryMeta)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetWithMeta indicates an expected call of GetWithMeta.
+func (mr *MockMetaGetterMockRecorder) GetWithMeta(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithMeta", reflect.TypeOf((*MockMetaGetter)(nil).GetWithMeta), key)
+}
+
+// PeekMeta mocks base method.
+func (m *MockMetaGetter) PeekMeta(key interface{}) (cache.EntryMeta, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PeekMeta", key)
+	ret0, _ := ret[0].(cache.EntryMeta)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// PeekMeta indicates an expected call of PeekMeta.
+func (mr *MockMetaGetterMockRecorder) PeekMeta(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PeekMeta", reflect.TypeOf((*MockMetaGetter)(nil).PeekMeta), key)
+}
+
+// MockBatchGetter is a mock of BatchGetter interface.
+type MockBatchGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchGetterMockRecorder
+}
+
+// MockBatchGetterMockRecorder is the mock recorder for MockBatchGetter.
+type MockBatchGetterMockRecorder struct {
+	mock *MockBatchGetter
+}
+
+// NewMockBatchGetter creates a new mock instance.
+func NewMockBatchGetter(ctrl *gomock.Controller) *MockBatchGetter {
+	mock := &MockBatchGetter{ctrl: ctrl}
+	mock.recorder = &MockBatchGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatchGetter) EXPECT() *MockBatchGetterMockRecorder {
+	return m.recorder
+}
+
+// GetMany mocks base method.
+func (m *MockBatchGetter) GetMany(keys []interface{}) (map[interface{}]interface{}, []interface{}) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMany", keys)
+	ret0, _ := ret[0].(map[interface{}]interface{})
+	ret1, _ := ret[1].([]interface{})
+	return ret0, ret1
+}
+
+// GetMany indicates an expected call of GetMany.
+func (mr *MockBatchGetterMockRecorder) GetMany(keys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMany", reflect.TypeOf((*MockBatchGetter)(nil).GetMany), keys)
+}
+
+// MockBatchPutter is a mock of BatchPutter interface.
+type MockBatchPutter struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchPutterMockRecorder
+}
+
+// MockBatchPutterMockRecorder is the mock recorder for MockBatchPutter.
+type MockBatchPutterMockRecorder struct {
+	mock *MockBatchPutter
+}
+
+// NewMockBatchPutter creates a new mock instance.
+func NewMockBatchPutter(ctrl *gomock.Controller) *MockBatchPutter {
+	mock := &MockBatchPutter{ctrl: ctrl}
+	mock.recorder = &MockBatchPutterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatchPutter) EXPECT() *MockBatchPutterMockRecorder {
+	return m.recorder
+}
+
+// PutMany mocks base method.
+func (m *MockBatchPutter) PutMany(items []cache.KeyValue) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PutMany", items)
+}
+
+// PutMany indicates an expected call of PutMany.
+func (mr *MockBatchPutterMockRecorder) PutMany(items interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutMany", reflect.TypeOf((*MockBatchPutter)(nil).PutMany), items)
+}
+
+// RemoveMany mocks base method.
+func (m *MockBatchPutter) RemoveMany(keys []interface{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveMany", keys)
+}
+
+// RemoveMany indicates an expected call of RemoveMany.
+func (mr *MockBatchPutterMockRecorder) RemoveMany(keys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMany", reflect.TypeOf((*MockBatchPutter)(nil).RemoveMany), keys)
+}
+
+// MockTagPutter is a mock of TagPutter interface.
+type MockTagPutter struct {
+	ctrl     *gomock.Controller
+	recorder *MockTagPutterMockRecorder
+}
+
+// MockTagPutterMockRecorder is the mock recorder for MockTagPutter.
+type MockTagPutterMockRecorder struct {
+	mock *MockTagPutter
+}
+
+// NewMockTagPutter creates a new mock instance.
+func NewMockTagPutter(ctrl *gomock.Controller) *MockTagPutter {
+	mock := &MockTagPutter{ctrl: ctrl}
+	mock.recorder = &MockTagPutterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTagPutter) EXPECT() *MockTagPutterMockRecorder {
+	return m.recorder
+}
+
+// PutWithTags mocks base method.
+func (m *MockTagPutter) PutWithTags(key, value interface{}, tags ...string) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key, value}
+	for _, a := range tags {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "PutWithTags", varargs...)
+}
+
+// PutWithTags indicates an expected call of PutWithTags.
+func (mr *MockTagPutterMockRecorder) PutWithTags(key, value interface{}, tags ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key, value}, tags...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWithTags", reflect.TypeOf((*MockTagPutter)(nil).PutWithTags), varargs...)
+}
+
+// MockTagInvalidator is a mock of TagInvalidator interface.
+type MockTagInvalidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockTagInvalidatorMockRecorder
+}
+
+// MockTagInvalidatorMockRecorder is the mock recorder for MockTagInvalidator.
+type MockTagInvalidatorMockRecorder struct {
+	mock *MockTagInvalidator
+}
+
+// NewMockTagInvalidator creates a new mock instance.
+func NewMockTagInvalidator(ctrl *gomock.Controller) *MockTagInvalidator {
+	mock := &MockTagInvalidator{ctrl: ctrl}
+	mock.recorder = &MockTagInvalidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTagInvalidator) EXPECT() *MockTagInvalidatorMockRecorder {
+	return m.recorder
+}
+
+// InvalidateTag mocks base method.
+func (m *MockTagInvalidator) InvalidateTag(tag string) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateTag", tag)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// InvalidateTag indicates an expected call of InvalidateTag.
+func (mr *MockTagInvalidatorMockRecorder) InvalidateTag(tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateTag", reflect.TypeOf((*MockTagInvalidator)(nil).InvalidateTag), tag)
+}
+
+// MockPrefixRemover is a mock of PrefixRemover interface.
+type MockPrefixRemover struct {
+	ctrl     *gomock.Controller
+	recorder *MockPrefixRemoverMockRecorder
+}
+
+// MockPrefixRemoverMockRecorder is the mock recorder for MockPrefixRemover.
+type MockPrefixRemoverMockRecorder struct {
+	mock *MockPrefixRemover
+}
+
+// NewMockPrefixRemover creates a new mock instance.
+func NewMockPrefixRemover(ctrl *gomock.Controller) *MockPrefixRemover {
+	mock := &MockPrefixRemover{ctrl: ctrl}
+	mock.recorder = &MockPrefixRemoverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPrefixRemover) EXPECT() *MockPrefixRemoverMockRecorder {
+	return m.recorder
+}
+
+// RemoveByPrefix mocks base method.
+func (m *MockPrefixRemover) RemoveByPrefix(prefix string) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveByPrefix", prefix)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// RemoveByPrefix indicates an expected call of RemoveByPrefix.
+func (mr *MockPrefixRemoverMockRecorder) RemoveByPrefix(prefix interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveByPrefix", reflect.TypeOf((*MockPrefixRemover)(nil).RemoveByPrefix), prefix)
+}
+
+// RemoveMatching mocks base method.
+func (m *MockPrefixRemover) RemoveMatching(match func(interface{}) bool) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMatching", match)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// RemoveMatching indicates an expected call of RemoveMatching.
+func (mr *MockPrefixRemoverMockRecorder) RemoveMatching(match interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMatching", reflect.TypeOf((*MockPrefixRemover)(nil).RemoveMatching), match)
+}
+
+// MockPinner is a mock of Pinner interface.
+type MockPinner struct {
+	ctrl     *gomock.Controller
+	recorder *MockPinnerMockRecorder
+}
+
+// MockPinnerMockRecorder is the mock recorder for MockPinner.
+type MockPinnerMockRecorder struct {
+	mock *MockPinner
+}
+
+// NewMockPinner creates a new mock instance.
+func NewMockPinner(ctrl *gomock.Controller) *MockPinner {
+	mock := &MockPinner{ctrl: ctrl}
+	mock.recorder = &MockPinnerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPinner) EXPECT() *MockPinnerMockRecorder {
+	return m.recorder
+}
+
+// PutPinned mocks base method.
+func (m *MockPinner) PutPinned(key, value interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutPinned", key, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutPinned indicates an expected call of PutPinned.
+func (mr *MockPinnerMockRecorder) PutPinned(key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutPinned", reflect.TypeOf((*MockPinner)(nil).PutPinned), key, value)
+}
+
+// Unpin mocks base method.
+func (m *MockPinner) Unpin(key interface{}) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unpin", key)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Unpin indicates an expected call of Unpin.
+func (mr *MockPinnerMockRecorder) Unpin(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unpin", reflect.TypeOf((*MockPinner)(nil).Unpin), key)
+}
+
+// MockAtomicUpdater is a mock of AtomicUpdater interface.
+type MockAtomicUpdater struct {
+	ctrl     *gomock.Controller
+	recorder *MockAtomicUpdaterMockRecorder
+}
+
+// MockAtomicUpdaterMockRecorder is the mock recorder for MockAtomicUpdater.
+type MockAtomicUpdaterMockRecorder struct {
+	mock *MockAtomicUpdater
+}
+
+// NewMockAtomicUpdater creates a new mock instance.
+func NewMockAtomicUpdater(ctrl *gomock.Controller) *MockAtomicUpdater {
+	mock := &MockAtomicUpdater{ctrl: ctrl}
+	mock.recorder = &MockAtomicUpdaterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAtomicUpdater) EXPECT() *MockAtomicUpdaterMockRecorder {
+	return m.recorder
+}
+
+// CompareAndSwap mocks base method.
+func (m *MockAtomicUpdater) CompareAndSwap(key, oldValue, newValue interface{}) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompareAndSwap", key, oldValue, newValue)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// CompareAndSwap indicates an expected call of CompareAndSwap.
+func (mr *MockAtomicUpdaterMockRecorder) CompareAndSwap(key, oldValue, newValue interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareAndSwap", reflect.TypeOf((*MockAtomicUpdater)(nil).CompareAndSwap), key, oldValue, newValue)
+}
+
+// PutIfAbsent mocks base method.
+func (m *MockAtomicUpdater) PutIfAbsent(key, value interface{}) (interface{}, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutIfAbsent", key, value)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// PutIfAbsent indicates an expected call of PutIfAbsent.
+func (mr *MockAtomicUpdaterMockRecorder) PutIfAbsent(key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutIfAbsent", reflect.TypeOf((*MockAtomicUpdater)(nil).PutIfAbsent), key, value)
+}
+
+// Update mocks base method.
+func (m *MockAtomicUpdater) Update(key interface{}, fn func(interface{}, bool) (interface{}, bool)) (interface{}, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", key, fn)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockAtomicUpdaterMockRecorder) Update(key, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockAtomicUpdater)(nil).Update), key, fn)
+}
+
+// MockVersioner is a mock of Versioner interface.
+type MockVersioner struct {
+	ctrl     *gomock.Controller
+	recorder *MockVersionerMockRecorder
+}
+
+// MockVersionerMockRecorder is the mock recorder for MockVersioner.
+type MockVersionerMockRecorder struct {
+	mock *MockVersioner
+}
+
+// NewMockVersioner creates a new mock instance.
+func NewMockVersioner(ctrl *gomock.Controller) *MockVersioner {
+	mock := &MockVersioner{ctrl: ctrl}
+	mock.recorder = &MockVersionerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVersioner) EXPECT() *MockVersionerMockRecorder {
+	return m.recorder
+}
+
+// GetVersioned mocks base method.
+func (m *MockVersioner) GetVersioned(key interface{}) (interface{}, uint64, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVersioned", key)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(uint64)
+	ret2, _ := ret[2].(bool)
+	return ret0, ret1, ret2
+}
+
+// GetVersioned indicates an expected call of GetVersioned.
+func (mr *MockVersionerMockRecorder) GetVersioned(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersioned", reflect.TypeOf((*MockVersioner)(nil).GetVersioned), key)
+}
+
+// PutIfVersion mocks base method.
+func (m *MockVersioner) PutIfVersion(key, value interface{}, expectedVersion uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutIfVersion", key, value, expectedVersion)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutIfVersion indicates an expected call of PutIfVersion.
+func (mr *MockVersionerMockRecorder) PutIfVersion(key, value, expectedVersion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutIfVersion", reflect.TypeOf((*MockVersioner)(nil).PutIfVersion), key, value, expectedVersion)
+}
+
+// MockLeaser is a mock of Leaser interface.
+type MockLeaser struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaserMockRecorder
+}
+
+// MockLeaserMockRecorder is the mock recorder for MockLeaser.
+type MockLeaserMockRecorder struct {
+	mock *MockLeaser
+}
+
+// NewMockLeaser creates a new mock instance.
+func NewMockLeaser(ctrl *gomock.Controller) *MockLeaser {
+	mock := &MockLeaser{ctrl: ctrl}
+	mock.recorder = &MockLeaserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLeaser) EXPECT() *MockLeaserMockRecorder {
+	return m.recorder
+}
+
+// GetWithLease mocks base method.
+func (m *MockLeaser) GetWithLease(key interface{}) (interface{}, uint64, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithLease", key)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(uint64)
+	ret2, _ := ret[2].(bool)
+	return ret0, ret1, ret2
+}
+
+// GetWithLease indicates an expected call of GetWithLease.
+func (mr *MockLeaserMockRecorder) GetWithLease(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithLease", reflect.TypeOf((*MockLeaser)(nil).GetWithLease), key)
+}
+
+// PutWithLease mocks base method.
+func (m *MockLeaser) PutWithLease(key, value interface{}, lease uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutWithLease", key, value, lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutWithLease indicates an expected call of PutWithLease.
+func (mr *MockLeaserMockRecorder) PutWithLease(key, value, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWithLease", reflect.TypeOf((*MockLeaser)(nil).PutWithLease), key, value, lease)
+}
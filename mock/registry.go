@@ -90,3 +90,40 @@ func (mr *MockRegistryMockRecorder) Register(caches ...interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockRegistry)(nil).Register), caches...)
 }
+
+// MockRegistryLister is a mock of RegistryLister interface.
+type MockRegistryLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockRegistryListerMockRecorder
+}
+
+// MockRegistryListerMockRecorder is the mock recorder for MockRegistryLister.
+type MockRegistryListerMockRecorder struct {
+	mock *MockRegistryLister
+}
+
+// NewMockRegistryLister creates a new mock instance.
+func NewMockRegistryLister(ctrl *gomock.Controller) *MockRegistryLister {
+	mock := &MockRegistryLister{ctrl: ctrl}
+	mock.recorder = &MockRegistryListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRegistryLister) EXPECT() *MockRegistryListerMockRecorder {
+	return m.recorder
+}
+
+// List mocks base method.
+func (m *MockRegistryLister) List() []cache.NamedCache {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]cache.NamedCache)
+	return ret0
+}
+
+// List indicates an expected call of List.
+func (mr *MockRegistryListerMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRegistryLister)(nil).List))
+}